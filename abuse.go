@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Requests are counted in slots of abuseSlotDuration, enough of them to
+	// cover the longest window.
+	abuseSlotDuration = 10 * time.Second
+	abuseSlotCount    = int(time.Hour / abuseSlotDuration)
+
+	// The first block of an IP address lasts abuseBlockDuration, every
+	// further one twice as long as the one before, up to abuseMaxBlockDuration.
+	abuseBlockDuration    = time.Minute
+	abuseMaxBlockDuration = 24 * time.Hour
+
+	maxAbuseCounters = 10000
+)
+
+// AbuseWindow is a request limit over a rolling window. A limit of zero
+// disables the window.
+type AbuseWindow struct {
+	Name     string
+	Duration time.Duration
+	Limit    int
+}
+
+// abuseCounter is a circular buffer of the request counts of an IP address
+// per slot. A slot is reused once its own slot number has passed.
+type abuseCounter struct {
+	counts [abuseSlotCount]int
+	slots  [abuseSlotCount]int64
+
+	lastRequest  time.Time
+	blockedUntil time.Time
+	blocks       int
+}
+
+// count returns the number of requests within the last duration up to slot.
+func (abuseCounter *abuseCounter) count(slot int64, duration time.Duration) int {
+	count := 0
+
+	for past := int64(0); past < int64(duration/abuseSlotDuration); past++ {
+		if index := (slot - past) % int64(abuseSlotCount); abuseCounter.slots[index] == slot-past {
+			count += abuseCounter.counts[index]
+		}
+	}
+
+	return count
+}
+
+// AbuseDetector complements the token buckets of the RateLimiter, which only
+// smooth out short bursts, by counting the requests of every IP address over
+// longer rolling windows. An address exceeding any of them is blocked for a
+// while, for longer every time it is blocked again.
+type AbuseDetector struct {
+	windows []AbuseWindow
+
+	mutex    sync.Mutex
+	counters map[string]*abuseCounter
+}
+
+func newAbuseDetector(windows []AbuseWindow) *AbuseDetector {
+	return &AbuseDetector{windows: windows, counters: map[string]*abuseCounter{}}
+}
+
+// allow counts a request of ip and returns how long ip is blocked, which is
+// zero if the request is allowed. Requests while blocked are not counted.
+func (abuseDetector *AbuseDetector) allow(ip string) time.Duration {
+	abuseDetector.mutex.Lock()
+	defer abuseDetector.mutex.Unlock()
+
+	now := time.Now()
+
+	if len(abuseDetector.counters) >= maxAbuseCounters {
+		for counterIp, counter := range abuseDetector.counters {
+			// Counters of blocked addresses are kept, and with them how often
+			// they were blocked, until an hour after their block.
+			if now.Sub(counter.lastRequest) > time.Hour && now.Sub(counter.blockedUntil) > time.Hour {
+				delete(abuseDetector.counters, counterIp)
+			}
+		}
+	}
+
+	counter, exists := abuseDetector.counters[ip]
+
+	if !exists {
+		counter = &abuseCounter{}
+		abuseDetector.counters[ip] = counter
+	}
+
+	if now.Before(counter.blockedUntil) {
+		return counter.blockedUntil.Sub(now)
+	}
+
+	slot := now.UnixNano() / int64(abuseSlotDuration)
+	index := slot % int64(abuseSlotCount)
+
+	if counter.slots[index] != slot {
+		counter.slots[index] = slot
+		counter.counts[index] = 0
+	}
+
+	counter.counts[index]++
+	counter.lastRequest = now
+
+	for _, window := range abuseDetector.windows {
+		if window.Limit <= 0 {
+			continue
+		}
+
+		count := counter.count(slot, window.Duration)
+
+		if count <= window.Limit {
+			continue
+		}
+
+		blockDuration := min(abuseBlockDuration<<counter.blocks, abuseMaxBlockDuration)
+
+		// Further blocks start at the cap, which must not overflow.
+		if blockDuration < abuseMaxBlockDuration {
+			counter.blocks++
+		}
+
+		counter.blockedUntil = now.Add(blockDuration)
+
+		// Counting starts over once the block ends, instead of the same
+		// requests triggering the next block right away.
+		counter.counts = [abuseSlotCount]int{}
+
+		logWarnf("Blocking IP address %s for %s: %d requests within %s exceed %s=%d", ip, blockDuration, count, window.Duration, window.Name, window.Limit)
+
+		return blockDuration
+	}
+
+	return 0
+}
+
+// abuseMiddleware rejects requests of blocked IP addresses before they reach
+// the rate limiter.
+func (server *Server) abuseMiddleware(abuseDetector *AbuseDetector, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if blockDuration := abuseDetector.allow(remoteIP(httpRequest)); blockDuration > 0 {
+			setRetryAfterHeader(httpResponseWriter, blockDuration)
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusTooManyRequests, ErrorIdTemporarilyBlocked, "IP address is temporarily blocked for too many requests")
+			return
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+const maxBatchSize = 1000
+
+// batchChunkSize is the number of domains looked up and written at once when
+// a batch is streamed, instead of being encoded as a whole.
+const batchChunkSize = 100
+
+const (
+	ndjsonContentType    = "application/x-ndjson"
+	multipartContentType = "multipart/mixed"
+)
+
+type BatchHttpRequest struct {
+	Domains []string `json:"domains"`
+}
+
+// batchLookup looks up domains on up to workers goroutines and returns the
+// results in the same order, like publicsuffix.BatchLookup does. Once ctx is
+// done, the remaining domains fail with ctx.Err().
+func batchLookup(ctx context.Context, domains []string, options publicsuffix.Options, workers int) ([]publicsuffix.Result, error) {
+	results := make([]publicsuffix.Result, len(domains))
+	errs := make([]error, len(domains))
+
+	if workers > len(domains) {
+		workers = len(domains)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	waitGroup := sync.WaitGroup{}
+
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for index := range indexes {
+				results[index], errs[index] = publicsuffix.LookupContext(ctx, domains[index], options)
+			}
+		}()
+	}
+
+	for index := range domains {
+		indexes <- index
+	}
+
+	close(indexes)
+	waitGroup.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for index, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("domain at index %d: %w", index, err)
+		}
+	}
+
+	return results, nil
+}
+
+// groupByIsManagedBy partitions results into their isManagedBy values. Every
+// value is present, even without results, so that clients need not check.
+func groupByIsManagedBy(results []publicsuffix.Result) map[string][]publicsuffix.Result {
+	groups := map[string][]publicsuffix.Result{
+		publicsuffix.ManagedByIcann:         {},
+		publicsuffix.ManagedByPrivateEntity: {},
+		publicsuffix.ManagedByNone:          {},
+	}
+
+	for _, result := range results {
+		groups[result.IsManagedBy] = append(groups[result.IsManagedBy], result)
+	}
+
+	return groups
+}
+
+// streamBatchLookup looks up domains in chunks of batchChunkSize and passes
+// each chunk to writeChunk as soon as it is looked up, flushing the response
+// in between. header is only sent along once the first chunk turned out
+// valid, as an invalid domain in it still yields a JSON error, and false is
+// returned. A later invalid domain aborts the response.
+func (server *Server) streamBatchLookup(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, domains []string, header http.Header, writeChunk func(results []publicsuffix.Result) error) bool {
+	responseController := http.NewResponseController(httpResponseWriter)
+
+	// Co-occurrence only considers distinct registrable domains, so only
+	// those results are kept across chunks.
+	recorded := []publicsuffix.Result{}
+	seen := map[string]bool{}
+
+	for start := 0; start < len(domains); start += batchChunkSize {
+		end := start + batchChunkSize
+
+		if end > len(domains) {
+			end = len(domains)
+		}
+
+		results, err := batchLookup(httpRequest.Context(), domains[start:end], server.config().LookupOptions, server.config().BatchWorkers)
+
+		if err := httpRequest.Context().Err(); err != nil {
+			logDebugf("Client went away after %d streamed results: %s", start, err)
+			return false
+		}
+
+		if err != nil && start == 0 {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain: %s", err))
+			return false
+		}
+
+		if err != nil {
+			logErrorf("Invalid domain after %d streamed results: %s", start, err)
+			panic(http.ErrAbortHandler)
+		}
+
+		if start == 0 {
+			for name, values := range header {
+				httpResponseWriter.Header()[name] = values
+			}
+
+			// Clients must not wait for the whole body to guess its type.
+			httpResponseWriter.Header().Set("X-Content-Type-Options", "nosniff")
+
+			// Proxies and CDNs must neither request ranges of nor buffer a
+			// stream.
+			httpResponseWriter.Header().Set("Accept-Ranges", "none")
+			httpResponseWriter.Header().Set("Cache-Control", "no-cache, no-store")
+
+			// HTTP/1.0 has no chunked encoding and HTTP/2 frames the body itself.
+			if httpRequest.ProtoMajor == 1 && httpRequest.ProtoMinor >= 1 {
+				httpResponseWriter.Header().Set("Transfer-Encoding", "chunked")
+			}
+		}
+
+		for _, result := range results {
+			if domain := registrableDomain(result); domain != "" && !seen[domain] {
+				seen[domain] = true
+				recorded = append(recorded, result)
+			}
+		}
+
+		if err := writeChunk(results); err != nil {
+			logErrorf("Writing streamed results failed: %s", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		if err := responseController.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logErrorf("Flushing streamed results failed: %s", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	server.cooccurrence.record(recorded)
+
+	return true
+}
+
+func acceptsNDJSON(httpRequest *http.Request) bool {
+	return strings.Contains(httpRequest.Header.Get("Accept"), ndjsonContentType)
+}
+
+func acceptsMultipart(httpRequest *http.Request) bool {
+	return strings.Contains(httpRequest.Header.Get("Accept"), multipartContentType)
+}
+
+// acceptsStreamedBatch reports whether the batch is streamed instead of being
+// encoded as a whole.
+func acceptsStreamedBatch(httpRequest *http.Request) bool {
+	return acceptsParquet(httpRequest) || acceptsNDJSON(httpRequest) || acceptsMultipart(httpRequest)
+}
+
+// writeNDJSONBatchHttpResponse writes every result as its own line of JSON,
+// so that clients can process the batch while it is looked up.
+//
+// See: https://github.com/ndjson/ndjson-spec
+func (server *Server) writeNDJSONBatchHttpResponse(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, domains []string, fields []string) {
+	encoder := server.newJSONEncoder(httpResponseWriter)
+
+	header := http.Header{}
+	header.Set("Content-Type", ndjsonContentType)
+
+	server.streamBatchLookup(httpResponseWriter, httpRequest, domains, header, func(results []publicsuffix.Result) error {
+		localize(httpResponseWriter, results)
+
+		for _, result := range results {
+			var line any = result
+
+			if fields != nil {
+				sparseResult, err := server.selectFields(result, fields)
+
+				if err != nil {
+					return err
+				}
+
+				line = sparseResult
+			}
+
+			if err := encoder.Encode(line); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// writeMultipartBatchHttpResponse writes every result as its own body part of
+// JSON, identified by the index of its domain as Content-ID, e.g. `<0>`.
+//
+// See: https://www.rfc-editor.org/rfc/rfc2046#section-5.1.3
+func (server *Server) writeMultipartBatchHttpResponse(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, domains []string, fields []string) {
+	multipartWriter := multipart.NewWriter(httpResponseWriter)
+	index := 0
+
+	header := http.Header{}
+	header.Set("Content-Type", fmt.Sprintf("%s; boundary=%s", multipartContentType, multipartWriter.Boundary()))
+
+	streamed := server.streamBatchLookup(httpResponseWriter, httpRequest, domains, header, func(results []publicsuffix.Result) error {
+		localize(httpResponseWriter, results)
+
+		for _, result := range results {
+			var part any = result
+
+			if fields != nil {
+				sparseResult, err := server.selectFields(result, fields)
+
+				if err != nil {
+					return err
+				}
+
+				part = sparseResult
+			}
+
+			partWriter, err := multipartWriter.CreatePart(textproto.MIMEHeader{
+				"Content-Type": {jsonContentType},
+				"Content-Id":   {"<" + strconv.Itoa(index) + ">"},
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if err := server.newJSONEncoder(partWriter).Encode(part); err != nil {
+				return err
+			}
+
+			index++
+		}
+
+		return nil
+	})
+
+	if streamed {
+		multipartWriter.Close()
+	}
+}
+
+// batchHttpHandler looks up the domains of a JSON body on POST, or of the
+// repeated `domain` URL query parameter on GET.
+func (server *Server) batchHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	batchHttpRequest := BatchHttpRequest{}
+
+	switch httpRequest.Method {
+	case http.MethodGet, http.MethodHead:
+		batchHttpRequest.Domains = httpRequest.URL.Query()["domain"]
+
+		if len(batchHttpRequest.Domains) == 0 {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `domain`")
+			return
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(httpResponseWriter, httpRequest.Body, 1<<20)).Decode(&batchHttpRequest); err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedBody, fmt.Sprintf("Malformed request body: %s", err))
+			return
+		}
+
+		if len(batchHttpRequest.Domains) == 0 {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedBody, "Request body field `domains` must not be empty")
+			return
+		}
+
+		recordBatch(httpResponseWriter, batchHttpRequest.Domains, server.config().LookupOptions)
+	default:
+		httpResponseWriter.Header().Set("Allow", "GET, HEAD, POST")
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusMethodNotAllowed, ErrorIdMethodNotAllowed, fmt.Sprintf("Method `%s` is not allowed at path `%s`", httpRequest.Method, httpRequest.URL.Path))
+		return
+	}
+
+	if len(batchHttpRequest.Domains) > maxBatchSize {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdBatchTooLarge, fmt.Sprintf("At most %d domains can be looked up at once", maxBatchSize))
+		return
+	}
+
+	groupBy := httpRequest.URL.Query().Get("groupBy")
+
+	if groupBy != "" && groupBy != "isManagedBy" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `groupBy`, expected `isManagedBy`")
+		return
+	}
+
+	fields, err := parseFields(httpRequest)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, fmt.Sprintf("Malformed URL query parameter `fields`: %s", err))
+		return
+	}
+
+	if httpRequest.URL.Query().Get("dry-run") == "true" {
+		dryRunHttpResponses, err := batchDryRun(batchHttpRequest.Domains, server.config().LookupOptions)
+
+		if err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain: %s", err))
+			return
+		}
+
+		server.writeHttpResponse(httpResponseWriter, httpRequest, dryRunHttpResponses)
+		return
+	}
+
+	// Streamed results carry isManagedBy themselves, so groupBy does not apply,
+	// nor does fields to the fixed schema of Parquet.
+	if acceptsParquet(httpRequest) {
+		server.writeParquetBatchHttpResponse(httpResponseWriter, httpRequest, batchHttpRequest.Domains)
+		return
+	}
+
+	if acceptsNDJSON(httpRequest) {
+		server.writeNDJSONBatchHttpResponse(httpResponseWriter, httpRequest, batchHttpRequest.Domains, fields)
+		return
+	}
+
+	if acceptsMultipart(httpRequest) {
+		server.writeMultipartBatchHttpResponse(httpResponseWriter, httpRequest, batchHttpRequest.Domains, fields)
+		return
+	}
+
+	results, err := batchLookup(httpRequest.Context(), batchHttpRequest.Domains, server.config().LookupOptions, server.config().BatchWorkers)
+
+	// The connection is gone, so there is no one to respond to.
+	if err := httpRequest.Context().Err(); err != nil {
+		logDebugf("Client went away during batch lookup: %s", err)
+		return
+	}
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain: %s", err))
+		return
+	}
+
+	server.cooccurrence.record(results)
+	localize(httpResponseWriter, results)
+
+	var batchHttpResponse any = results
+
+	if groupBy == "isManagedBy" {
+		batchHttpResponse = groupByIsManagedBy(results)
+	}
+
+	if fields != nil {
+		batchHttpResponse, err = server.selectBatchFields(batchHttpResponse, fields)
+
+		if err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusInternalServerError, ErrorIdInternal, fmt.Sprintf("Selecting fields failed: %s", err))
+			return
+		}
+	}
+
+	server.writeHttpResponse(httpResponseWriter, httpRequest, batchHttpResponse)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+func BenchmarkBatchLookup(b *testing.B) {
+	domains := make([]string, maxBatchSize)
+
+	for index := range domains {
+		domains[index] = fmt.Sprintf("www%d.example.co.uk", index)
+	}
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := batchLookup(context.Background(), domains, publicsuffix.Options{}, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchLookupKeepsOrder(t *testing.T) {
+	domains := []string{"a.example.com", "b.example.co.uk", "c.example.org", "d.example.de"}
+
+	results, err := batchLookup(context.Background(), domains, publicsuffix.Options{}, 3)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index, result := range results {
+		if result.Domain != domains[index] {
+			t.Errorf("results[%d].Domain = %q, want %q", index, result.Domain, domains[index])
+		}
+	}
+}
+
+func TestStreamedBatchHeaders(t *testing.T) {
+	server := NewServer(loadConfig())
+
+	for _, accept := range []string{ndjsonContentType, multipartContentType, parquetContentType} {
+		t.Run(accept, func(t *testing.T) {
+			httpRequest := httptest.NewRequest(http.MethodPost, "/publicsuffix/batch", strings.NewReader(`{"domains":["www.example.co.uk","example.com"]}`))
+			httpRequest.Header.Set("Accept", accept)
+			httpResponseRecorder := httptest.NewRecorder()
+
+			server.batchHttpHandler(httpResponseRecorder, httpRequest)
+
+			if httpResponseRecorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", httpResponseRecorder.Code, http.StatusOK, httpResponseRecorder.Body.String())
+			}
+
+			if acceptRanges := httpResponseRecorder.Header().Get("Accept-Ranges"); acceptRanges != "none" {
+				t.Errorf("Accept-Ranges = %q, want none", acceptRanges)
+			}
+
+			if cacheControl := httpResponseRecorder.Header().Get("Cache-Control"); !strings.Contains(cacheControl, "no-store") {
+				t.Errorf("Cache-Control = %q, want no-store", cacheControl)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+var canaryFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "publicsuffix_canary_failures_total",
+	Help: "Number of canary lookups that returned an unexpected isManagedBy value.",
+})
+
+type CanaryDomain struct {
+	Domain              string
+	ExpectedIsManagedBy string
+}
+
+type CanaryStatus struct {
+	mutex       sync.Mutex
+	lastRunTime time.Time
+	lastRunOk   bool
+}
+
+var canaryStatus CanaryStatus
+
+// parseCanaryDomains parses a comma-separated list of `domain=isManagedBy`
+// pairs such as `example.com=ICANN,blogspot.com=PRIVATE_ENTITY`.
+func parseCanaryDomains(value string) []CanaryDomain {
+	canaryDomains := []CanaryDomain{}
+
+	for _, pair := range strings.Split(value, ",") {
+		domain, expectedIsManagedBy, found := strings.Cut(strings.TrimSpace(pair), "=")
+
+		if !found || domain == "" || expectedIsManagedBy == "" {
+			logWarnf("ignoring malformed canary domain %q, expected `domain=isManagedBy`", pair)
+			continue
+		}
+
+		canaryDomains = append(canaryDomains, CanaryDomain{
+			Domain:              domain,
+			ExpectedIsManagedBy: expectedIsManagedBy,
+		})
+	}
+
+	return canaryDomains
+}
+
+func runCanaryLookups(canaryDomains []CanaryDomain, options publicsuffix.Options) {
+	ok := true
+
+	for _, canaryDomain := range canaryDomains {
+		result, err := publicsuffix.Lookup(canaryDomain.Domain, options)
+
+		if err != nil {
+			logErrorf("canary lookup for %s failed: %s", canaryDomain.Domain, err)
+		} else if result.IsManagedBy != canaryDomain.ExpectedIsManagedBy {
+			logErrorf("canary lookup for %s returned isManagedBy %s, expected %s", canaryDomain.Domain, result.IsManagedBy, canaryDomain.ExpectedIsManagedBy)
+		} else {
+			continue
+		}
+
+		ok = false
+		canaryFailuresTotal.Inc()
+	}
+
+	canaryStatus.mutex.Lock()
+	defer canaryStatus.mutex.Unlock()
+
+	canaryStatus.lastRunTime = time.Now()
+	canaryStatus.lastRunOk = ok
+}
+
+func startCanary(canaryDomains []CanaryDomain, interval time.Duration, options publicsuffix.Options) {
+	go func() {
+		for {
+			runCanaryLookups(canaryDomains, options)
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,9 @@
+//go:build cgo
+
+package main
+
+// cgoEnabled reports whether the binary was built with cgo, which makes the
+// DNS resolver use the one of the C library instead of the pure Go one.
+//
+// See: https://pkg.go.dev/net#hdr-Name_Resolution
+const cgoEnabled = true
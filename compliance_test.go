@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// complianceFuzzInputs are query values that have tripped up domain parsers
+// before, on top of which checkHandlerCompliance sends random ones.
+var complianceFuzzInputs = []string{
+	"", ".", "..", "a..b", "*.com", "!www.ck", "xn--", "xn--zz", "\x00", "\xff\xfe",
+	"%", "%zz", "https://", "[::1]", "example.com:99999",
+	strings.Repeat("a", 64) + ".com", strings.Repeat("a.", 200) + "com",
+	"例え.テスト", "ëxample.com", "‮example.com",
+}
+
+// complianceFuzzRunes are what the random query values are made of.
+var complianceFuzzRunes = []rune("abc019.-_*!:/@%?#[] \x00é例�")
+
+// checkHandlerCompliance checks that the handler of path answers a request
+// without query parameters with 200 or 400 and never with a 5xx for any value
+// of the given query parameters, always with a JSON body.
+//
+// Unlike asked for, CORS headers are not checked, as the service sets none:
+// it is not meant to be called from browsers of other origins.
+func checkHandlerCompliance(t *testing.T, path string, queryParameters []string) {
+	t.Helper()
+
+	httpResponse, body := integrationRequest(t, http.MethodGet, path, "", nil)
+
+	if httpResponse.StatusCode != http.StatusOK && httpResponse.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET %s: status = %d, want 200 or 400: %s", path, httpResponse.StatusCode, body)
+	}
+
+	if contentType := httpResponse.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("GET %s: Content-Type = %q, want application/json", path, contentType)
+	}
+
+	random := rand.New(rand.NewPCG(uint64(len(path)), 0))
+	inputs := append([]string{}, complianceFuzzInputs...)
+
+	for range 20 {
+		input := make([]rune, random.IntN(80))
+
+		for i := range input {
+			input[i] = complianceFuzzRunes[random.IntN(len(complianceFuzzRunes))]
+		}
+
+		inputs = append(inputs, string(input))
+	}
+
+	for _, queryParameter := range queryParameters {
+		for _, input := range inputs {
+			query := url.Values{queryParameter: {input}}
+			httpResponse, body := integrationRequest(t, http.MethodGet, path+"?"+query.Encode(), "", nil)
+
+			if httpResponse.StatusCode >= http.StatusInternalServerError {
+				t.Errorf("GET %s?%s: status = %d, want no 5xx: %s", path, query.Encode(), httpResponse.StatusCode, body)
+			}
+
+			if contentType := httpResponse.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+				t.Errorf("GET %s?%s: Content-Type = %q, want application/json", path, query.Encode(), contentType)
+			}
+		}
+	}
+}
+
+func TestHandlerCompliance(t *testing.T) {
+	tests := []struct {
+		path            string
+		queryParameters []string
+	}{
+		{"/publicsuffix", []string{"domain"}},
+		{"/migrate", []string{"from", "domain"}},
+		{"/history", []string{"domain"}},
+		{"/suffixlist/diff", []string{"from", "to"}},
+		{"/similarity", []string{"domain1", "domain2"}},
+		{"/tree", []string{"domain"}},
+		{"/tld/", nil},
+		{"/tld/com", nil},
+		{"/health", nil},
+		{"/version", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			checkHandlerCompliance(t, test.path, test.queryParameters)
+		})
+	}
+}
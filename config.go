@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configFileValues holds the values of CONFIG_FILE, a JSON object or TOML
+// file keyed by environment variable names such as `{"PORT": "8080"}` or
+// `PORT = 8080`. This lets operators mount a single Kubernetes ConfigMap
+// instead of setting every variable. See config.example.toml for all of them.
+var (
+	configFileValues = map[string]string{}
+
+	// configFileMutex guards configFileValues, which are replaced when the
+	// configuration is reloaded.
+	configFileMutex sync.RWMutex
+)
+
+// lookupEnv is like os.LookupEnv but falls back to the config file, so that
+// environment variables always take precedence.
+func lookupEnv(key string) (string, bool) {
+	if value, exists := os.LookupEnv(key); exists {
+		return value, true
+	}
+
+	configFileMutex.RLock()
+	defer configFileMutex.RUnlock()
+
+	value, exists := configFileValues[key]
+
+	return value, exists
+}
+
+// loadConfigFile replaces the config file values with those of path, unless
+// it cannot be read or is invalid.
+func loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{}
+
+	if strings.HasSuffix(path, ".toml") {
+		err = loadTOMLConfigFile(path, data, values)
+	} else {
+		err = loadJSONConfigFile(path, data, values)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	configFileMutex.Lock()
+	defer configFileMutex.Unlock()
+
+	configFileValues = values
+
+	return nil
+}
+
+func loadJSONConfigFile(path string, data []byte, values map[string]string) error {
+	rawValues := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(data, &rawValues); err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+
+		if errors.As(err, &syntaxError) {
+			return fmt.Errorf("%s:%d: %w", path, lineOfOffset(data, syntaxError.Offset), err)
+		} else if errors.As(err, &unmarshalTypeError) {
+			return fmt.Errorf("%s:%d: %w", path, lineOfOffset(data, unmarshalTypeError.Offset), err)
+		}
+
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for key, rawValue := range rawValues {
+		// Numbers and booleans are accepted as is, e.g. `{"PORT": 8080}`.
+		value := string(rawValue)
+
+		if unquotedValue, err := strconv.Unquote(value); err == nil {
+			value = unquotedValue
+		} else if bytes.HasPrefix(rawValue, []byte("{")) || bytes.HasPrefix(rawValue, []byte("[")) || value == "null" {
+			return fmt.Errorf("%s:%d: value of %s must be a string, number or boolean", path, lineOfOffset(data, int64(bytes.Index(data, rawValue))), key)
+		}
+
+		values[key] = value
+	}
+
+	return nil
+}
+
+// loadTOMLConfigFile reads top-level keys only. Besides strings, numbers and
+// booleans, arrays are accepted for the comma-separated variables, e.g.
+// `DEPRECATED_PATHS = ["/v0/", "/old/"]`.
+//
+// See: https://toml.io/en/v1.0.0
+func loadTOMLConfigFile(path string, data []byte, values map[string]string) error {
+	tomlValues := map[string]any{}
+	_, err := toml.Decode(string(data), &tomlValues)
+
+	// Parse errors already state the line, e.g. `toml: line 2: ...`.
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for key, value := range tomlValues {
+		switch value := value.(type) {
+		case string:
+			values[key] = value
+		case int64, float64, bool:
+			values[key] = fmt.Sprint(value)
+		case []any:
+			items := make([]string, len(value))
+
+			for index, item := range value {
+				items[index] = fmt.Sprint(item)
+			}
+
+			values[key] = strings.Join(items, ",")
+		default:
+			return fmt.Errorf("%s: value of %s must be a string, number, boolean or array", path, key)
+		}
+	}
+
+	return nil
+}
+
+func lineOfOffset(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
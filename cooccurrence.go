@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+const (
+	maxCooccurrenceDomains = 100
+	topCooccurrencePairs   = 50
+)
+
+type CooccurrencePair struct {
+	Domains [2]string `json:"domains"`
+	Count   int       `json:"count"`
+}
+
+// Cooccurrence counts how often two registrable domains are looked up in the
+// same batch, which hints at infrastructure clusters. The counts are cleared
+// every resetInterval to bound their memory.
+type Cooccurrence struct {
+	resetInterval time.Duration
+
+	mutex   sync.Mutex
+	counts  map[[2]string]int
+	resetAt time.Time
+}
+
+func newCooccurrence(resetInterval time.Duration) *Cooccurrence {
+	return &Cooccurrence{
+		resetInterval: resetInterval,
+		counts:        map[[2]string]int{},
+		resetAt:       time.Now().Add(resetInterval),
+	}
+}
+
+func (cooccurrence *Cooccurrence) resetIfDue() {
+	if time.Now().Before(cooccurrence.resetAt) {
+		return
+	}
+
+	cooccurrence.counts = map[[2]string]int{}
+	cooccurrence.resetAt = time.Now().Add(cooccurrence.resetInterval)
+}
+
+// record counts every pair of registrable domains among results. Only the
+// first maxCooccurrenceDomains distinct ones are considered, as the number of
+// pairs grows quadratically.
+func (cooccurrence *Cooccurrence) record(results []publicsuffix.Result) {
+	domains := []string{}
+	seen := map[string]bool{}
+
+	for _, result := range results {
+		domain := registrableDomain(result)
+
+		if domain == "" || seen[domain] {
+			continue
+		}
+
+		seen[domain] = true
+		domains = append(domains, domain)
+
+		if len(domains) == maxCooccurrenceDomains {
+			break
+		}
+	}
+
+	sort.Strings(domains)
+
+	cooccurrence.mutex.Lock()
+	defer cooccurrence.mutex.Unlock()
+
+	cooccurrence.resetIfDue()
+
+	for first := range domains {
+		for second := first + 1; second < len(domains); second++ {
+			cooccurrence.counts[[2]string{domains[first], domains[second]}]++
+		}
+	}
+}
+
+func (cooccurrence *Cooccurrence) top(limit int) []CooccurrencePair {
+	cooccurrence.mutex.Lock()
+	defer cooccurrence.mutex.Unlock()
+
+	cooccurrence.resetIfDue()
+
+	pairs := make([]CooccurrencePair, 0, len(cooccurrence.counts))
+
+	for domains, count := range cooccurrence.counts {
+		pairs = append(pairs, CooccurrencePair{Domains: domains, Count: count})
+	}
+
+	sort.Slice(pairs, func(first int, second int) bool {
+		if pairs[first].Count != pairs[second].Count {
+			return pairs[first].Count > pairs[second].Count
+		}
+
+		return pairs[first].Domains[0]+pairs[first].Domains[1] < pairs[second].Domains[0]+pairs[second].Domains[1]
+	})
+
+	if len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+
+	return pairs
+}
+
+// registrableDomain returns the public suffix of result plus one label, or an
+// empty string when the domain is a public suffix itself.
+func registrableDomain(result publicsuffix.Result) string {
+	if result.IsPublicSuffix {
+		return ""
+	}
+
+	labels := strings.Split(strings.TrimSuffix(result.Domain, "."+result.PublicSuffix), ".")
+
+	return labels[len(labels)-1] + "." + result.PublicSuffix
+}
+
+// adminHandler only lets requests with `Authorization: Bearer <adminToken>`
+// through. Without an adminToken the admin endpoints are disabled.
+func (server *Server) adminHandler(adminToken string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if adminToken == "" {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusNotFound, ErrorIdNotFound, "Not found")
+			return
+		}
+
+		token, found := strings.CutPrefix(httpRequest.Header.Get("Authorization"), "Bearer ")
+
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			httpResponseWriter.Header().Set("WWW-Authenticate", "Bearer")
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnauthorized, ErrorIdUnauthorized, "Missing or invalid admin token")
+			return
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
+
+func (server *Server) cooccurrenceHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(server.cooccurrence.top(topCooccurrencePairs))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		statusCode    int
+		errorId       string
+	}{
+		{"disabled", "", "Bearer secret", http.StatusNotFound, ErrorIdNotFound},
+		{"missing token", "secret", "", http.StatusUnauthorized, ErrorIdUnauthorized},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized, ErrorIdUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusOK, ""},
+	}
+
+	server := NewServer(Config{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := server.adminHandler(test.adminToken, http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {}))
+			httpRequest := httptest.NewRequest(http.MethodGet, "/admin/cooccurrence", nil)
+			httpRequest.Header.Set("Authorization", test.authorization)
+			httpResponseRecorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(httpResponseRecorder, httpRequest)
+
+			if httpResponseRecorder.Code != test.statusCode {
+				t.Fatalf("status = %d, want %d", httpResponseRecorder.Code, test.statusCode)
+			}
+
+			if test.errorId == "" {
+				return
+			}
+
+			if contentType := httpResponseRecorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", contentType)
+			}
+
+			var errorHttpResponse ErrorHttpResponse
+
+			if err := json.Unmarshal(httpResponseRecorder.Body.Bytes(), &errorHttpResponse); err != nil {
+				t.Fatalf("body is no JSON: %s", err)
+			}
+
+			if errorHttpResponse.ErrorId != test.errorId {
+				t.Errorf("errorId = %s, want %s", errorHttpResponse.ErrorId, test.errorId)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// deduplicationWindow is how long the response of a POST is replayed to
+// identical requests after it completed, in addition to while in flight.
+const deduplicationWindow = time.Second
+
+var deduplicatedRequestsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "publicsuffix_deduplicated_requests_total",
+	Help: "Number of POST requests answered with the response of an identical concurrent request.",
+})
+
+// DeduplicatedResponse is the response of the first of identical requests.
+// It is complete once done is closed, unless aborted is set.
+type DeduplicatedResponse struct {
+	done       chan struct{}
+	aborted    bool
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+}
+
+// Deduplicator lets identical concurrent POST requests, e.g. a batch retried
+// by a client before the response arrived, wait for the first one and replay
+// its response instead of being processed again. Unlike idempotency keys it
+// requires nothing from clients.
+type Deduplicator struct {
+	window    time.Duration
+	responses sync.Map
+}
+
+func newDeduplicator(window time.Duration) *Deduplicator {
+	return &Deduplicator{window: window}
+}
+
+// recordingHttpResponseWriter passes a response through while recording it.
+type recordingHttpResponseWriter struct {
+	http.ResponseWriter
+	response *DeduplicatedResponse
+}
+
+func (httpResponseWriter *recordingHttpResponseWriter) WriteHeader(statusCode int) {
+	if httpResponseWriter.response.statusCode == 0 {
+		httpResponseWriter.response.statusCode = statusCode
+		httpResponseWriter.response.header = httpResponseWriter.Header().Clone()
+	}
+
+	httpResponseWriter.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (httpResponseWriter *recordingHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.response.statusCode == 0 {
+		httpResponseWriter.WriteHeader(http.StatusOK)
+	}
+
+	httpResponseWriter.response.body.Write(data)
+
+	return httpResponseWriter.ResponseWriter.Write(data)
+}
+
+func (httpResponseWriter *recordingHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+// requestHash identifies a request by everything that can change its
+// response.
+func requestHash(httpRequest *http.Request, body []byte) string {
+	hash := sha256.New()
+
+	fmt.Fprintf(hash, "%s %s\nAccept: %s\n\n", httpRequest.Method, httpRequest.URL.RequestURI(), httpRequest.Header.Get("Accept"))
+	hash.Write(body)
+
+	return string(hash.Sum(nil))
+}
+
+func (server *Server) deduplicationHandler(deduplicator *Deduplicator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if httpRequest.Method != http.MethodPost {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(httpResponseWriter, httpRequest.Body, 1<<20))
+
+		if err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedBody, fmt.Sprintf("Malformed request body: %s", err))
+			return
+		}
+
+		httpRequest.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := requestHash(httpRequest, body)
+		response := &DeduplicatedResponse{done: make(chan struct{})}
+
+		if inFlight, loaded := deduplicator.responses.LoadOrStore(key, response); loaded {
+			inFlight := inFlight.(*DeduplicatedResponse)
+
+			// The connection is gone, so there is no one to replay to.
+			select {
+			case <-inFlight.done:
+			case <-httpRequest.Context().Done():
+				logDebugf("Client went away waiting for an identical request: %s", httpRequest.Context().Err())
+				return
+			}
+
+			if !inFlight.aborted {
+				deduplicatedRequestsTotal.Inc()
+				replayResponse(httpResponseWriter, inFlight)
+				return
+			}
+
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				response.aborted = true
+				deduplicator.responses.Delete(key)
+				close(response.done)
+
+				panic(recovered)
+			}
+
+			close(response.done)
+			time.AfterFunc(deduplicator.window, func() { deduplicator.responses.Delete(key) })
+		}()
+
+		next.ServeHTTP(&recordingHttpResponseWriter{ResponseWriter: httpResponseWriter, response: response}, httpRequest)
+	})
+}
+
+// replayResponse writes a recorded response. Headers the middlewares already
+// set for this request, e.g. the rate limit, are kept.
+func replayResponse(httpResponseWriter http.ResponseWriter, response *DeduplicatedResponse) {
+	for name, values := range response.header {
+		if _, ok := httpResponseWriter.Header()[name]; !ok {
+			httpResponseWriter.Header()[name] = values
+		}
+	}
+
+	statusCode := response.statusCode
+
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	httpResponseWriter.WriteHeader(statusCode)
+	httpResponseWriter.Write(response.body.Bytes())
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeduplicationHandler(t *testing.T) {
+	var processed atomic.Int32
+	release := make(chan struct{})
+
+	server := NewServer(Config{})
+	handler := server.deduplicationHandler(newDeduplicator(time.Second), http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		processed.Add(1)
+		<-release
+		httpResponseWriter.Write([]byte(`{"publicSuffix":"co.uk"}`))
+	}))
+
+	httpResponseRecorders := []*httptest.ResponseRecorder{httptest.NewRecorder(), httptest.NewRecorder()}
+	waitGroup := sync.WaitGroup{}
+
+	for _, httpResponseRecorder := range httpResponseRecorders {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			httpRequest := httptest.NewRequest(http.MethodPost, "/publicsuffix/batch", strings.NewReader(`{"domains":["example.co.uk"]}`))
+			handler.ServeHTTP(httpResponseRecorder, httpRequest)
+		}()
+	}
+
+	// Both requests are in flight before the first one completes.
+	for processed.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	waitGroup.Wait()
+
+	if processed.Load() != 1 {
+		t.Errorf("processed %d requests, want 1", processed.Load())
+	}
+
+	for index, httpResponseRecorder := range httpResponseRecorders {
+		if httpResponseRecorder.Code != http.StatusOK || httpResponseRecorder.Body.String() != `{"publicSuffix":"co.uk"}` {
+			t.Errorf("response %d = %d %s", index+1, httpResponseRecorder.Code, httpResponseRecorder.Body.String())
+		}
+	}
+}
+
+func TestDeduplicationHandlerStopsWaitingForGoneClients(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server := NewServer(Config{})
+	handler := server.deduplicationHandler(newDeduplicator(time.Second), http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		<-release
+	}))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/publicsuffix/batch", strings.NewReader("{}")))
+
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/publicsuffix/batch", strings.NewReader("{}")).WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("the duplicate kept waiting after its client went away")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecationMiddleware(t *testing.T) {
+	server := NewServer(Config{})
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		path            string
+		deprecationDate time.Time
+		sunsetDate      time.Time
+		statusCode      int
+		deprecation     string
+		sunset          bool
+	}{
+		{"other path", "/health", time.Time{}, time.Time{}, http.StatusOK, "", false},
+		{"deprecated", "/v1/publicsuffix", time.Time{}, time.Time{}, http.StatusOK, "true", false},
+		{"deprecated since", "/v1/publicsuffix", now.Add(-time.Hour), time.Time{}, http.StatusOK, now.Add(-time.Hour).Format(http.TimeFormat), false},
+		{"before sunset", "/v1/publicsuffix", time.Time{}, now.Add(time.Hour), http.StatusOK, "true", true},
+		{"after sunset", "/v1/publicsuffix", time.Time{}, now.Add(-time.Hour), http.StatusGone, "true", true},
+		{"other path after sunset", "/health", time.Time{}, now.Add(-time.Hour), http.StatusOK, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+				httpResponseWriter.WriteHeader(http.StatusOK)
+			})
+
+			handler := server.deprecationMiddleware([]string{"/v1/"}, "https://example.com/deprecation", test.deprecationDate, test.sunsetDate, next)
+			httpResponseRecorder := httptest.NewRecorder()
+			handler.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			if httpResponseRecorder.Code != test.statusCode {
+				t.Errorf("status = %d, want %d", httpResponseRecorder.Code, test.statusCode)
+			}
+
+			if deprecation := httpResponseRecorder.Header().Get("Deprecation"); deprecation != test.deprecation {
+				t.Errorf("Deprecation = %q, want %q", deprecation, test.deprecation)
+			}
+
+			if sunset := httpResponseRecorder.Header().Get("Sunset") != ""; sunset != test.sunset {
+				t.Errorf("Sunset set = %t, want %t", sunset, test.sunset)
+			}
+
+			if link := httpResponseRecorder.Header().Get("Link"); (link != "") != (test.deprecation != "") {
+				t.Errorf("Link = %q, want it only on deprecated paths", link)
+			}
+		})
+	}
+}
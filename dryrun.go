@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// DryRunHttpResponse answers a lookup requested with `?dry-run=true`, which
+// only normalizes and validates the domain, so that clients can test their
+// own normalization against the service.
+type DryRunHttpResponse struct {
+	Domain         string `json:"domain"`
+	Valid          bool   `json:"valid"`
+	NormalizedFrom string `json:"normalizedFrom"`
+}
+
+func dryRun(domain string, options publicsuffix.Options) (DryRunHttpResponse, error) {
+	normalizedDomain, err := publicsuffix.Validate(domain, options)
+
+	if err != nil {
+		return DryRunHttpResponse{}, err
+	}
+
+	return DryRunHttpResponse{Domain: normalizedDomain, Valid: true, NormalizedFrom: domain}, nil
+}
+
+// batchDryRun validates domains like batchLookup looks them up, stopping at
+// the first invalid one.
+func batchDryRun(domains []string, options publicsuffix.Options) ([]DryRunHttpResponse, error) {
+	dryRunHttpResponses := make([]DryRunHttpResponse, len(domains))
+
+	for index, domain := range domains {
+		dryRunHttpResponse, err := dryRun(domain, options)
+
+		if err != nil {
+			return nil, fmt.Errorf("domain at index %d: %w", index, err)
+		}
+
+		dryRunHttpResponses[index] = dryRunHttpResponse
+	}
+
+	return dryRunHttpResponses, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const maxErrorMessageLength = 200
+
+// acceptsHtml reports whether the request comes from a browser rather than an
+// API client, which get the JSON error envelope instead of an error page.
+func acceptsHtml(httpRequest *http.Request) bool {
+	return strings.Contains(httpRequest.Header.Get("Accept"), "text/html")
+}
+
+// sanitizeErrorMessage keeps only the first line of an error message, so that
+// neither stack traces nor lengthy upstream responses reach the client.
+func sanitizeErrorMessage(errorMessage string) string {
+	errorMessage, _, _ = strings.Cut(errorMessage, "\n")
+
+	if runes := []rune(errorMessage); len(runes) > maxErrorMessageLength {
+		errorMessage = string(runes[:maxErrorMessageLength]) + "…"
+	}
+
+	return errorMessage
+}
+
+func (server *Server) writeNotFoundHtmlHttpResponse(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, errorMessage string) {
+	templateData := server.newTemplateData()
+	templateData.URL = httpRequest.URL.String()
+
+	html, err := server.renderTemplate(httpRequest, "404.html", templateData)
+
+	if err != nil {
+		logErrorf("rendering 404 page failed: %s", err)
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusNotFound, ErrorIdNotFound, errorMessage)
+		return
+	}
+
+	writeHtmlHttpResponse(httpResponseWriter, http.StatusNotFound, html)
+}
+
+// writeInternalErrorHttpResponse logs err and answers with a 500 error page or
+// JSON error envelope, depending on what the client accepts.
+func (server *Server) writeInternalErrorHttpResponse(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, err error) {
+	logErrorf("%s %s failed: %s", httpRequest.Method, httpRequest.URL.Path, err)
+
+	errorMessage := sanitizeErrorMessage(err.Error())
+
+	if !acceptsHtml(httpRequest) {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusInternalServerError, ErrorIdInternal, errorMessage)
+		return
+	}
+
+	templateData := server.newTemplateData()
+	templateData.URL = httpRequest.URL.String()
+	templateData.ErrorMessage = errorMessage
+
+	html, err := server.renderTemplate(httpRequest, "500.html", templateData)
+
+	if err != nil {
+		logErrorf("rendering 500 page failed: %s", err)
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusInternalServerError, ErrorIdInternal, errorMessage)
+		return
+	}
+
+	sentry.CaptureMessage(fmt.Sprintf("%s: %s", ErrorIdInternal, errorMessage))
+
+	writeHtmlHttpResponse(httpResponseWriter, http.StatusInternalServerError, html)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// Error IDs are sent as `errorId` in the error envelope. Unlike `errorCode`
+// they are stable across HTTP semantics, so clients may switch on them.
+const (
+	ErrorIdNotFound            = "NOT_FOUND"
+	ErrorIdUnauthorized        = "UNAUTHORIZED"
+	ErrorIdMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	ErrorIdGone                = "GONE"
+	ErrorIdInternal            = "INTERNAL_ERROR"
+	ErrorIdUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	ErrorIdLoopDetected        = "LOOP_DETECTED"
+	ErrorIdMalformedQuery      = "MALFORMED_QUERY"
+	ErrorIdMalformedBody       = "MALFORMED_BODY"
+	ErrorIdBatchTooLarge       = "BATCH_TOO_LARGE"
+	ErrorIdUploadTooLarge      = "UPLOAD_TOO_LARGE"
+	ErrorIdUploadNotUTF8       = "UPLOAD_NOT_UTF8"
+	ErrorIdResponseTooLarge    = "RESPONSE_TOO_LARGE"
+	ErrorIdRateLimitExceeded   = "RATE_LIMIT_EXCEEDED"
+	ErrorIdTemporarilyBlocked  = "TEMPORARILY_BLOCKED"
+	ErrorIdTLSVersionTooOld    = "TLS_VERSION_TOO_OLD"
+	ErrorIdTimeout             = "TIMEOUT"
+	ErrorIdDomainInvalid       = "DOMAIN_INVALID"
+	ErrorIdDomainEmpty         = "DOMAIN_EMPTY"
+	ErrorIdDomainTooLong       = "DOMAIN_TOO_LONG"
+	ErrorIdDomainTooManyLabels = "DOMAIN_TOO_MANY_LABELS"
+	ErrorIdDomainInvalidLabel  = "DOMAIN_INVALID_LABEL"
+	ErrorIdDomainUnderscore    = "DOMAIN_UNDERSCORE_LABEL"
+	ErrorIdTLDInvalid          = "TLD_INVALID"
+)
+
+var lookupErrorIds = []struct {
+	err     error
+	errorId string
+}{
+	{publicsuffix.ErrDomainEmpty, ErrorIdDomainEmpty},
+	{publicsuffix.ErrDomainTooLong, ErrorIdDomainTooLong},
+	{publicsuffix.ErrDomainTooManyLabels, ErrorIdDomainTooManyLabels},
+	{publicsuffix.ErrDomainEmptyLabel, ErrorIdDomainInvalidLabel},
+	{publicsuffix.ErrDomainUnderscoreLabel, ErrorIdDomainUnderscore},
+	{publicsuffix.ErrNotATLD, ErrorIdTLDInvalid},
+}
+
+// lookupErrorId returns the error ID of an error returned by the publicsuffix
+// package, falling back to fallback for errors without a dedicated one.
+func lookupErrorId(err error, fallback string) string {
+	for _, lookupErrorId := range lookupErrorIds {
+		if errors.Is(err, lookupErrorId.err) {
+			return lookupErrorId.errorId
+		}
+	}
+
+	return fallback
+}
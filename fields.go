@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// resultFields are the JSON field names of a lookup result, which can be
+// selected with `?fields=`.
+var resultFields = jsonFieldNames(reflect.TypeFor[publicsuffix.Result]())
+
+// SparseResult is a lookup result reduced to the fields selected with
+// `?fields=`.
+type SparseResult map[string]json.RawMessage
+
+func jsonFieldNames(structType reflect.Type) map[string]bool {
+	fieldNames := map[string]bool{}
+
+	for index := range structType.NumField() {
+		name, _, _ := strings.Cut(structType.Field(index).Tag.Get("json"), ",")
+
+		if name != "" && name != "-" {
+			fieldNames[name] = true
+		}
+	}
+
+	return fieldNames
+}
+
+// parseFields returns the field names of the comma-separated `fields` URL
+// query parameter, or nil when all fields are requested.
+func parseFields(httpRequest *http.Request) ([]string, error) {
+	fields := []string{}
+
+	for _, field := range strings.Split(httpRequest.URL.Query().Get("fields"), ",") {
+		field = strings.TrimSpace(field)
+
+		if field == "" {
+			continue
+		}
+
+		if !resultFields[field] {
+			return nil, fmt.Errorf("unknown field `%s`", field)
+		}
+
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
+
+// selectFields encodes result and drops every top-level key not in fields.
+// Optional fields that are unset stay absent.
+func (server *Server) selectFields(result publicsuffix.Result, fields []string) (SparseResult, error) {
+	buffer := bytes.Buffer{}
+
+	if err := server.newJSONEncoder(&buffer).Encode(result); err != nil {
+		return nil, err
+	}
+
+	encodedResult := SparseResult{}
+
+	if err := json.Unmarshal(buffer.Bytes(), &encodedResult); err != nil {
+		return nil, err
+	}
+
+	sparseResult := SparseResult{}
+
+	for _, field := range fields {
+		if value, ok := encodedResult[field]; ok {
+			sparseResult[field] = value
+		}
+	}
+
+	return sparseResult, nil
+}
+
+func (server *Server) selectFieldsOfAll(results []publicsuffix.Result, fields []string) ([]SparseResult, error) {
+	sparseResults := make([]SparseResult, len(results))
+
+	for index, result := range results {
+		sparseResult, err := server.selectFields(result, fields)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sparseResults[index] = sparseResult
+	}
+
+	return sparseResults, nil
+}
+
+// selectBatchFields applies selectFields to the results of a batch, which are
+// either a list or grouped by groupByIsManagedBy.
+func (server *Server) selectBatchFields(batchHttpResponse any, fields []string) (any, error) {
+	switch batchHttpResponse := batchHttpResponse.(type) {
+	case []publicsuffix.Result:
+		return server.selectFieldsOfAll(batchHttpResponse, fields)
+	case map[string][]publicsuffix.Result:
+		sparseGroups := map[string][]SparseResult{}
+
+		for isManagedBy, results := range batchHttpResponse {
+			sparseResults, err := server.selectFieldsOfAll(results, fields)
+
+			if err != nil {
+				return nil, err
+			}
+
+			sparseGroups[isManagedBy] = sparseResults
+		}
+
+		return sparseGroups, nil
+	default:
+		return nil, fmt.Errorf("unexpected batch response of type %T", batchHttpResponse)
+	}
+}
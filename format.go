@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	jsonContentType = "application/json; charset=utf-8"
+	cborContentType = "application/cbor"
+	yamlContentType = "application/yaml"
+)
+
+// negotiateFormat returns the content type of the response format accepted by
+// the client, which is JSON unless it asks for CBOR, a binary encoding of the
+// same data that is smaller on the wire, or YAML, e.g. for Ansible or Helm.
+//
+// See: https://www.rfc-editor.org/rfc/rfc8949
+// See: https://www.rfc-editor.org/rfc/rfc9512
+func negotiateFormat(httpRequest *http.Request) string {
+	accept := httpRequest.Header.Get("Accept")
+
+	switch {
+	case strings.Contains(accept, cborContentType):
+		return cborContentType
+	case strings.Contains(accept, yamlContentType), strings.Contains(accept, "text/yaml"):
+		return yamlContentType
+	}
+
+	return jsonContentType
+}
+
+// writeHttpResponse encodes httpResponse in the negotiated format. CBOR and
+// YAML use the JSON field names, so that clients can decode them with the same
+// schema.
+func (server *Server) writeHttpResponse(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, httpResponse any) {
+	httpResponseWriter.Header().Add("Vary", "Accept")
+
+	format := negotiateFormat(httpRequest)
+
+	if format == jsonContentType {
+		httpResponseWriter.Header().Add("Content-Type", jsonContentType)
+
+		server.newJSONEncoder(httpResponseWriter).Encode(httpResponse)
+		return
+	}
+
+	var data []byte
+	var err error
+
+	if format == cborContentType {
+		data, err = cbor.Marshal(httpResponse)
+	} else {
+		data, err = marshalYAML(httpResponse)
+	}
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusInternalServerError, ErrorIdInternal, fmt.Sprintf("Encoding %s failed: %s", format, err))
+		return
+	}
+
+	httpResponseWriter.Header().Add("Content-Type", format)
+	httpResponseWriter.Write(data)
+}
+
+// marshalYAML encodes value as JSON first and converts that to YAML, which
+// keeps the JSON field names and their order.
+func marshalYAML(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	node := yaml.Node{}
+
+	// JSON is valid YAML, in flow style though.
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+
+	blockStyle(&node)
+
+	return yaml.Marshal(&node)
+}
+
+// blockStyle drops the style of node and its children, so that they are
+// encoded in block style, with strings only quoted where necessary.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// MarshalCBOR decodes the JSON values of sparseResult again, as CBOR would
+// otherwise encode them as byte strings.
+func (sparseResult SparseResult) MarshalCBOR() ([]byte, error) {
+	values := map[string]any{}
+
+	for field, rawValue := range sparseResult {
+		decoder := json.NewDecoder(bytes.NewReader(rawValue))
+		decoder.UseNumber()
+
+		var value any
+
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		values[field] = cborValue(value)
+	}
+
+	return cbor.Marshal(values)
+}
+
+// cborValue turns the json.Number values of a decoded JSON value into
+// integers where possible, as CBOR distinguishes them from floats.
+func cborValue(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+
+		float, _ := value.Float64()
+
+		return float
+	case []any:
+		for index, item := range value {
+			value[index] = cborValue(item)
+		}
+	case map[string]any:
+		for key, item := range value {
+			value[key] = cborValue(item)
+		}
+	}
+
+	return value
+}
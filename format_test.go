@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"gopkg.in/yaml.v3"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	httpResponse, body := integrationRequest(t, http.MethodGet, "/publicsuffix?domain=www.example.co.uk", "", http.Header{"Accept": {cborContentType}})
+
+	if contentType := httpResponse.Header.Get("Content-Type"); contentType != cborContentType {
+		t.Fatalf("Content-Type = %q, want %s", contentType, cborContentType)
+	}
+
+	var result publicsuffix.Result
+
+	if err := cbor.Unmarshal(body, &result); err != nil {
+		t.Fatalf("body is no CBOR: %s", err)
+	}
+
+	if result.Domain != "www.example.co.uk" || result.PublicSuffix != "co.uk" || result.LabelCount != 4 || len(result.Labels) != 4 {
+		t.Errorf("result = %+v, want the lookup of www.example.co.uk", result)
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	httpResponse, body := integrationRequest(t, http.MethodGet, "/publicsuffix?domain=www.example.co.uk", "", http.Header{"Accept": {yamlContentType}})
+
+	if contentType := httpResponse.Header.Get("Content-Type"); contentType != yamlContentType {
+		t.Fatalf("Content-Type = %q, want %s", contentType, yamlContentType)
+	}
+
+	if strings.HasPrefix(string(body), "{") {
+		t.Errorf("body = %.20q, want block style", body)
+	}
+
+	// The YAML keeps the JSON field names, which Result only declares as
+	// json tags, so it is decoded through JSON.
+	var value any
+
+	if err := yaml.Unmarshal(body, &value); err != nil {
+		t.Fatalf("body is no YAML: %s", err)
+	}
+
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result publicsuffix.Result
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("YAML does not decode into a result: %s", err)
+	}
+
+	if result.Domain != "www.example.co.uk" || result.PublicSuffix != "co.uk" || result.LabelCount != 4 || len(result.Labels) != 4 {
+		t.Errorf("result = %+v, want the lookup of www.example.co.uk", result)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	outboundDNSSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "publicsuffix_outbound_dns_seconds",
+		Help: "Duration of DNS resolutions of outbound requests.",
+	}, []string{"target"})
+
+	outboundTCPSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "publicsuffix_outbound_tcp_seconds",
+		Help: "Duration of TCP connects of outbound requests.",
+	}, []string{"target"})
+
+	outboundTLSSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "publicsuffix_outbound_tls_seconds",
+		Help: "Duration of TLS handshakes of outbound requests.",
+	}, []string{"target"})
+
+	outboundFirstByteSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "publicsuffix_outbound_first_byte_seconds",
+		Help: "Duration from sending outbound requests until the first response byte.",
+	}, []string{"target"})
+)
+
+// withClientTrace records how long the DNS resolution, TCP connect, TLS
+// handshake and first response byte of httpRequest took. Reused connections
+// skip the first three, so they are only observed when they happen.
+func withClientTrace(httpRequest *http.Request, target string) *http.Request {
+	var mutex sync.Mutex
+	var dnsStart, connectStart, tlsStart time.Time
+	var dnsDuration, connectDuration, tlsDuration time.Duration
+
+	start := time.Now()
+
+	clientTrace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			dnsDuration = time.Since(dnsStart)
+			outboundDNSSeconds.WithLabelValues(target).Observe(dnsDuration.Seconds())
+		},
+		ConnectStart: func(string, string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			connectStart = time.Now()
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err == nil {
+				connectDuration = time.Since(connectStart)
+				outboundTCPSeconds.WithLabelValues(target).Observe(connectDuration.Seconds())
+			}
+		},
+		TLSHandshakeStart: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err == nil {
+				tlsDuration = time.Since(tlsStart)
+				outboundTLSSeconds.WithLabelValues(target).Observe(tlsDuration.Seconds())
+			}
+		},
+		GotFirstResponseByte: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			firstByteDuration := time.Since(start)
+			outboundFirstByteSeconds.WithLabelValues(target).Observe(firstByteDuration.Seconds())
+
+			logDebugf(
+				"%s %s: target=%s dns=%s tcp=%s tls=%s firstByte=%s",
+				httpRequest.Method, httpRequest.URL.Redacted(), target,
+				dnsDuration, connectDuration, tlsDuration, firstByteDuration,
+			)
+		},
+	}
+
+	return httpRequest.WithContext(httptrace.WithClientTrace(httpRequest.Context(), clientTrace))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const integrationAdminToken = "integration-admin-token"
+
+// integrationServer serves the whole middleware chain of loadConfig(), as
+// the binary does, to the integration tests.
+var integrationServer *httptest.Server
+
+func TestMain(m *testing.M) {
+	config := loadConfig()
+	config.AdminToken = integrationAdminToken
+
+	integrationServer = httptest.NewServer(NewServer(config))
+
+	code := m.Run()
+
+	integrationServer.Close()
+	os.Exit(code)
+}
+
+// integrationRequest sends a request to integrationServer and returns its
+// response along with the body read.
+func integrationRequest(t *testing.T, method string, path string, body string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+
+	httpRequest, err := http.NewRequest(method, integrationServer.URL+path, strings.NewReader(body))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, values := range header {
+		httpRequest.Header[name] = values
+	}
+
+	httpResponse, err := integrationServer.Client().Do(httpRequest)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return httpResponse, responseBody
+}
+
+// jsonField decodes body as a JSON object and returns its field name.
+func jsonField(t *testing.T, body []byte, name string) any {
+	t.Helper()
+
+	object := map[string]any{}
+
+	if err := json.Unmarshal(body, &object); err != nil {
+		t.Fatalf("body is no JSON object: %s", err)
+	}
+
+	return object[name]
+}
+
+type integrationTest struct {
+	name        string
+	method      string
+	path        string
+	body        string
+	header      http.Header
+	statusCode  int
+	contentType string
+	checkBody   func(t *testing.T, body []byte)
+}
+
+var integrationTests = []integrationTest{
+	{
+		name: "index", method: http.MethodGet, path: "/",
+		statusCode: http.StatusOK, contentType: "text/html",
+		checkBody: func(t *testing.T, body []byte) {
+			if !strings.HasPrefix(string(body), "<!DOCTYPE html>") {
+				t.Errorf("body starts with %.20q, want a HTML document", body)
+			}
+		},
+	},
+	{
+		name: "health", method: http.MethodGet, path: "/health",
+		statusCode: http.StatusOK, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			if status := jsonField(t, body, "status"); status != "ok" && status != "degraded" {
+				t.Errorf("status = %v, want ok or degraded", status)
+			}
+		},
+	},
+	{
+		name: "version", method: http.MethodGet, path: "/version",
+		statusCode: http.StatusOK, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			if goVersion, _ := jsonField(t, body, "goVersion").(string); !strings.HasPrefix(goVersion, "go") {
+				t.Errorf("goVersion = %q, want a Go version", goVersion)
+			}
+		},
+	},
+	{
+		name: "public suffix", method: http.MethodGet, path: "/publicsuffix?domain=www.example.co.uk",
+		statusCode: http.StatusOK, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			if publicSuffix := jsonField(t, body, "publicSuffix"); publicSuffix != "co.uk" {
+				t.Errorf("publicSuffix = %v, want co.uk", publicSuffix)
+			}
+		},
+	},
+	{
+		name: "batch", method: http.MethodPost, path: "/publicsuffix/batch", body: `{"domains":["www.example.co.uk","example.com"]}`,
+		header:     http.Header{"Content-Type": {"application/json"}},
+		statusCode: http.StatusOK, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			results := []map[string]any{}
+
+			if err := json.Unmarshal(body, &results); err != nil {
+				t.Fatalf("body is no JSON array: %s", err)
+			}
+
+			if len(results) != 2 || results[1]["publicSuffix"] != "com" {
+				t.Errorf("results = %v, want 2 with com last", results)
+			}
+		},
+	},
+	{
+		name: "suffix list diff", method: http.MethodGet, path: "/suffixlist/diff?from=2024-01&to=2024-04",
+		statusCode: http.StatusOK, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			if from, to := jsonField(t, body, "from"), jsonField(t, body, "to"); from != "2023-07-09" || to != "2024-03-01" {
+				t.Errorf("from, to = %v, %v, want 2023-07-09, 2024-03-01", from, to)
+			}
+		},
+	},
+	{
+		name: "favicon", method: http.MethodGet, path: "/favicon.ico",
+		statusCode: http.StatusOK, contentType: "image/x-icon",
+		checkBody: func(t *testing.T, body []byte) {
+			if len(body) == 0 {
+				t.Error("body is empty")
+			}
+		},
+	},
+	{
+		name: "robots.txt", method: http.MethodGet, path: "/robots.txt",
+		statusCode: http.StatusOK, contentType: "text/plain",
+		checkBody: func(t *testing.T, body []byte) {
+			if !strings.Contains(string(body), "User-agent:") {
+				t.Errorf("body = %q, want a User-agent line", body)
+			}
+		},
+	},
+	{
+		name: "static", method: http.MethodGet, path: "/static/style.css",
+		statusCode: http.StatusOK, contentType: "text/css",
+		checkBody: func(t *testing.T, body []byte) {
+			if !strings.Contains(string(body), "{") {
+				t.Errorf("body = %.20q, want CSS rules", body)
+			}
+		},
+	},
+	{
+		name: "admin", method: http.MethodGet, path: "/admin/cooccurrence",
+		header:     http.Header{"Authorization": {"Bearer " + integrationAdminToken}},
+		statusCode: http.StatusOK, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			pairs := []any{}
+
+			if err := json.Unmarshal(body, &pairs); err != nil {
+				t.Errorf("body is no JSON array: %s", err)
+			}
+		},
+	},
+	{
+		name: "admin without token", method: http.MethodGet, path: "/admin/cooccurrence",
+		statusCode: http.StatusUnauthorized, contentType: "application/json",
+		checkBody: func(t *testing.T, body []byte) {
+			if errorId := jsonField(t, body, "errorId"); errorId != ErrorIdUnauthorized {
+				t.Errorf("errorId = %v, want %s", errorId, ErrorIdUnauthorized)
+			}
+		},
+	},
+}
+
+func TestIntegration(t *testing.T) {
+	for _, test := range integrationTests {
+		t.Run(test.name, func(t *testing.T) {
+			httpResponse, body := integrationRequest(t, test.method, test.path, test.body, test.header)
+
+			if httpResponse.StatusCode != test.statusCode {
+				t.Fatalf("status = %d, want %d: %s", httpResponse.StatusCode, test.statusCode, body)
+			}
+
+			if contentType := httpResponse.Header.Get("Content-Type"); !strings.HasPrefix(contentType, test.contentType) {
+				t.Errorf("Content-Type = %q, want %s", contentType, test.contentType)
+			}
+
+			test.checkBody(t, body)
+		})
+	}
+}
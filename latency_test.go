@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSimulatedLatency(t *testing.T) {
+	tests := []struct {
+		name     string
+		devMode  bool
+		minDelay time.Duration
+	}{
+		{"dev mode", true, 50 * time.Millisecond},
+		{"production", false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := loadConfig()
+			config.DevMode = test.devMode
+			config.SimulatedLatency = 50 * time.Millisecond
+
+			server := NewServer(config)
+
+			startTime := time.Now()
+			httpResponseRecorder := httptest.NewRecorder()
+			server.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+			duration := time.Since(startTime)
+
+			if httpResponseRecorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", httpResponseRecorder.Code, http.StatusOK)
+			}
+
+			if duration < test.minDelay {
+				t.Errorf("request took %s, want at least %s", duration, test.minDelay)
+			}
+
+			if !test.devMode && duration >= config.SimulatedLatency {
+				t.Errorf("request took %s, want no simulated latency outside dev mode", duration)
+			}
+		})
+	}
+}
+
+func TestLatencyMiddlewareJitter(t *testing.T) {
+	latency, jitter := 20*time.Millisecond, 10*time.Millisecond
+
+	handler := latencyMiddleware(latency, jitter, http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {}))
+
+	for range 5 {
+		startTime := time.Now()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if duration := time.Since(startTime); duration < latency-jitter {
+			t.Errorf("request took %s, want at least %s", duration, latency-jitter)
+		}
+	}
+}
+
+func TestLatencyMiddlewareCancelled(t *testing.T) {
+	called := false
+
+	handler := latencyMiddleware(time.Hour, 0, http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		called = true
+	}))
+
+	httpRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, cancel := context.WithCancel(httpRequest.Context())
+	cancel()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httpRequest.WithContext(ctx))
+
+	if called {
+		t.Error("handler called after the request was cancelled")
+	}
+}
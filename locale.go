@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+const defaultLocale = "en"
+
+// knownLocales are the locales accepted by `?locale=`. Those without a file
+// in i18n/ yet are answered in English.
+var knownLocales = []string{"de", "en", "es", "fr", "it", "ja", "nl", "pl", "pt", "zh"}
+
+// Translation holds the human-readable labels of a locale. errorType is keyed
+// by status code and falls back to http.StatusText.
+type Translation struct {
+	IsManagedBy map[string]string `json:"isManagedBy"`
+	ErrorType   map[string]string `json:"errorType"`
+}
+
+// loadTranslation reads the translation of locale from the embedded i18n/
+// directory.
+func loadTranslation(locale string) (*Translation, error) {
+	data, err := fs.ReadFile(embededTranslationFileSystem, fmt.Sprintf("i18n/%s.json", locale))
+
+	if err != nil {
+		return nil, err
+	}
+
+	translation := &Translation{}
+
+	if err := json.Unmarshal(data, translation); err != nil {
+		return nil, fmt.Errorf("i18n/%s.json: %w", locale, err)
+	}
+
+	return translation, nil
+}
+
+// loadTranslations loads the translation of every known locale.
+func loadTranslations() map[string]*Translation {
+	translations := map[string]*Translation{}
+
+	for _, locale := range knownLocales {
+		translation, err := loadTranslation(locale)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			logErrorf("Loading translation failed: %s", err)
+			continue
+		}
+
+		translations[locale] = translation
+	}
+
+	// The fallback of every other locale must exist.
+	if translations[defaultLocale] == nil {
+		panic(fmt.Sprintf("missing translation i18n/%s.json", defaultLocale))
+	}
+
+	return translations
+}
+
+// localize sets the isManagedBy label of results in the translation requested
+// for the response written to httpResponseWriter, if any.
+func localize(httpResponseWriter http.ResponseWriter, results []publicsuffix.Result) {
+	translation := translationOf(httpResponseWriter)
+
+	if translation == nil {
+		return
+	}
+
+	for index := range results {
+		results[index].IsManagedByLabel = translation.IsManagedBy[results[index].IsManagedBy]
+	}
+}
+
+func (translation *Translation) errorType(statusCode int) string {
+	if errorType, ok := translation.ErrorType[strconv.Itoa(statusCode)]; ok {
+		return errorType
+	}
+
+	return http.StatusText(statusCode)
+}
+
+// localizedHttpResponseWriter carries the translation requested with
+// `?locale=` to writeErrorHttpResponse, which only gets to see the response
+// writer.
+type localizedHttpResponseWriter struct {
+	http.ResponseWriter
+	translation *Translation
+}
+
+func (httpResponseWriter *localizedHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+// translationOf returns the translation requested for the response written to
+// httpResponseWriter, or nil if none was requested.
+func translationOf(httpResponseWriter http.ResponseWriter) *Translation {
+	for {
+		switch current := httpResponseWriter.(type) {
+		case *localizedHttpResponseWriter:
+			return current.translation
+		case interface{ Unwrap() http.ResponseWriter }:
+			httpResponseWriter = current.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// localeMiddleware looks up the translation of the `locale` URL query
+// parameter, if any, for the handlers and error responses further down.
+func (server *Server) localeMiddleware(translations map[string]*Translation, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		locale := httpRequest.URL.Query().Get("locale")
+
+		if locale == "" {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		if !slices.Contains(knownLocales, locale) {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, fmt.Sprintf("Malformed URL query parameter `locale`, expected one of `%s`", strings.Join(knownLocales, "`, `")))
+			return
+		}
+
+		translation, ok := translations[locale]
+
+		if !ok {
+			translation = translations[defaultLocale]
+		}
+
+		next.ServeHTTP(&localizedHttpResponseWriter{ResponseWriter: httpResponseWriter, translation: translation}, httpRequest)
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+const (
+	logLevelDebug = iota
+	logLevelInfo
+	logLevelWarn
+	logLevelError
+)
+
+var logLevel = logLevelInfo
+
+func parseLogLevel(value string) int {
+	switch strings.ToLower(value) {
+	case "debug":
+		return logLevelDebug
+	case "warn":
+		return logLevelWarn
+	case "error":
+		return logLevelError
+	default:
+		return logLevelInfo
+	}
+}
+
+func logf(level int, prefix string, format string, args ...any) {
+	if level < logLevel {
+		return
+	}
+
+	log.Printf(prefix+" "+format, args...)
+}
+
+func logDebugf(format string, args ...any) { logf(logLevelDebug, "DEBUG", format, args...) }
+func logInfof(format string, args ...any)  { logf(logLevelInfo, "INFO", format, args...) }
+func logWarnf(format string, args ...any)  { logf(logLevelWarn, "WARN", format, args...) }
+func logErrorf(format string, args ...any) { logf(logLevelError, "ERROR", format, args...) }
@@ -1,25 +1,43 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"embed"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	"golang.org/x/net/publicsuffix"
+	"github.com/getsentry/sentry-go"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
 )
 
+// snapshotDatePattern matches the dates of snapshots at /suffixlist/diff,
+// given as year, month or day.
+var snapshotDatePattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
 var (
 	//go:embed template/*
 	embededTemplateFileSystem embed.FS
 
 	//go:embed static/*
 	embededStaticFileSystem embed.FS
+
+	//go:embed i18n/*.json
+	embededTranslationFileSystem embed.FS
 )
 
 func redirectHttpHandler(url string) func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
@@ -28,92 +46,447 @@ func redirectHttpHandler(url string) func(httpResponseWriter http.ResponseWriter
 	}
 }
 
-func indexHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+// templateFuncMap holds the helpers available in the templates, see
+// template/index.html for examples.
+var templateFuncMap = template.FuncMap{
+	"truncate":    truncate,
+	"toUpper":     strings.ToUpper,
+	"toLower":     strings.ToLower,
+	"joinStrings": joinStrings,
+}
+
+// truncate shortens value to at most max characters, ending in `…` when cut.
+func truncate(value string, max int) string {
+	runes := []rune(value)
+
+	if max <= 0 || len(runes) <= max {
+		return value
+	}
+
+	return string(runes[:max-1]) + "…"
+}
+
+func joinStrings(separator string, values ...string) string {
+	return strings.Join(values, separator)
+}
+
+// TemplateData is passed to all templates. URL and ErrorMessage are only set
+// for the error pages.
+type TemplateData struct {
+	DateTime        string
+	Year            int
+	SiteTitle       string
+	SiteDescription template.HTML
+	FooterText      template.HTML
+
+	URL          string
+	ErrorMessage string
+}
+
+func (server *Server) newTemplateData() TemplateData {
+	now := time.Now().In(server.config().DisplayLocation)
+
+	return TemplateData{
+		DateTime:  now.Format("2006-01-02 15:04:05"),
+		Year:      now.Year(),
+		SiteTitle: getEnv("SITE_TITLE", "PublicSuffix"),
+		SiteDescription: getEnvHTML("SITE_DESCRIPTION", `Dieser Dienst ist ein webbasiertes Äquivalent zum
+      <a href="https://pkg.go.dev/golang.org/x/net/publicsuffix"><code>publicsuffix</code></a>-Modul in Go.`),
+		FooterText: getEnvHTML("FOOTER_TEXT", template.HTML(fmt.Sprintf(
+			`&#169; %d <a href="https://stefanco.de">Stefan Kühnel</a>, Alle Rechte vorbehalten.`,
+			now.Year(),
+		))),
+	}
+}
+
+// contextWriter fails every write once its context is done, which aborts a
+// template execution that writes past its deadline.
+type contextWriter struct {
+	ctx    context.Context
+	buffer bytes.Buffer
+}
+
+func (writer *contextWriter) Write(data []byte) (int, error) {
+	if err := writer.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return writer.buffer.Write(data)
+}
+
+// renderTemplate renders the template name within the configured template
+// timeout. The result is buffered, so that a template that fails halfway does
+// not leave a partial page behind.
+func (server *Server) renderTemplate(httpRequest *http.Request, name string, templateData TemplateData) ([]byte, error) {
+	template, err := template.New(name).Funcs(templateFuncMap).ParseFS(embededTemplateFileSystem, "template/"+name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(httpRequest.Context(), server.config().TemplateTimeout)
+	defer cancel()
+
+	contextWriter := &contextWriter{ctx: ctx}
+
+	if err := template.Execute(contextWriter, templateData); err != nil {
+		return nil, err
+	}
+
+	return contextWriter.buffer.Bytes(), nil
+}
+
+func writeHtmlHttpResponse(httpResponseWriter http.ResponseWriter, statusCode int, html []byte) {
+	httpResponseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	httpResponseWriter.WriteHeader(statusCode)
+
+	httpResponseWriter.Write(html)
+}
+
+func (server *Server) indexHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
 	if httpRequest.URL.Path != "/" {
 		http.NotFound(httpResponseWriter, httpRequest)
 		return
 	}
 
-	template := template.Must(template.ParseFS(embededTemplateFileSystem, "template/index.html"))
-
-	type TemplateData struct {
-		DateTime string
-		Year     int
-	}
+	html, err := server.renderTemplate(httpRequest, "index.html", server.newTemplateData())
 
-	templateData := TemplateData{
-		DateTime: time.Now().Format("2006-01-02 15:04:05"),
-		Year:     time.Now().Year(),
+	if err != nil {
+		server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, fmt.Errorf("rendering index page failed: %w", err))
+		return
 	}
 
-	template.Execute(httpResponseWriter, templateData)
+	writeHtmlHttpResponse(httpResponseWriter, http.StatusOK, html)
 }
 
-func publicSuffixHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+func (server *Server) publicSuffixHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
 	if httpRequest.URL.Path != "/publicsuffix" {
 		http.NotFound(httpResponseWriter, httpRequest)
 		return
 	}
 
-	type PublicSuffixHttpResponse struct {
-		Domain       string `json:"domain"`
-		PublicSuffix string `json:"publicSuffix"`
-		IsManagedBy  string `json:"isManagedBy"`
+	domain := httpRequest.URL.Query().Get("domain")
+
+	// Some embedded systems and older HTTP clients submit the domain as a form
+	// field instead, but the URL query parameter always takes precedence.
+	if domain == "" && strings.HasPrefix(httpRequest.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if err := httpRequest.ParseForm(); err == nil {
+			domain = httpRequest.PostForm.Get("domain")
+		}
 	}
 
-	publicSuffixHttpResponse := func(domain string) PublicSuffixHttpResponse {
-		publicSuffix, isIcannManaged := publicsuffix.PublicSuffix(domain)
+	if domain == "" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `domain`")
+		return
+	}
 
-		isManagedBy := ""
+	if httpRequest.URL.Query().Get("dry-run") == "true" {
+		dryRunHttpResponse, err := dryRun(domain, server.config().LookupOptions)
 
-		// See: https://pkg.go.dev/golang.org/x/net/publicsuffix#example-PublicSuffix-Manager
-		if isIcannManaged {
-			isManagedBy = "ICANN"
-		} else if strings.IndexByte(publicSuffix, '.') >= 0 {
-			isManagedBy = "PRIVATE_ENTITY"
-		} else {
-			isManagedBy = "NONE"
+		if err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain `%s`: %s", domain, err))
+			return
 		}
 
-		return PublicSuffixHttpResponse{
-			Domain:       domain,
-			PublicSuffix: publicSuffix,
-			IsManagedBy:  isManagedBy,
+		server.writeHttpResponse(httpResponseWriter, httpRequest, dryRunHttpResponse)
+		return
+	}
+
+	fields, err := parseFields(httpRequest)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, fmt.Sprintf("Malformed URL query parameter `fields`: %s", err))
+		return
+	}
+
+	options := server.config().LookupOptions
+	options.ResolveDNS = httpRequest.URL.Query().Get("resolveDNS") == "true"
+	options.AllCandidates = httpRequest.URL.Query().Get("allCandidates") == "true"
+	options.Explain = httpRequest.URL.Query().Get("explain") == "true"
+	options.Strict = httpRequest.URL.Query().Get("strict") == "true"
+
+	// A header rather than a URL query parameter, so that diagnostics do not
+	// end up in links or access logs by accident.
+	if server.config().DebugEndpoints {
+		httpResponseWriter.Header().Add("Vary", "X-Debug")
+		options.Diagnostics = httpRequest.Header.Get("X-Debug") == "true"
+	}
+
+	publicSuffixHttpResponse, err := server.lookupCoalescer.lookup(httpRequest.Context(), domain, options)
+
+	// The connection is gone, so there is no one to respond to.
+	if err := httpRequest.Context().Err(); err != nil {
+		logDebugf("Client went away during lookup of `%s`: %s", domain, err)
+		return
+	}
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain `%s`: %s", domain, err))
+		return
+	}
+
+	if server.mirror != nil && httpRequest.Header.Get(mirroredHeader) == "" {
+		query := httpRequest.URL.Query()
+		query.Set("domain", domain)
+
+		server.mirror.enqueue(query, publicSuffixHttpResponse)
+	}
+
+	if server.reputation != nil {
+		server.reputation.addTo(httpRequest.Context(), &publicSuffixHttpResponse)
+	}
+
+	if translation := translationOf(httpResponseWriter); translation != nil {
+		publicSuffixHttpResponse.IsManagedByLabel = translation.IsManagedBy[publicSuffixHttpResponse.IsManagedBy]
+	}
+
+	var httpResponse any = publicSuffixHttpResponse
+
+	if fields != nil {
+		httpResponse, err = server.selectFields(publicSuffixHttpResponse, fields)
+
+		if err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusInternalServerError, ErrorIdInternal, fmt.Sprintf("Selecting fields failed: %s", err))
+			return
 		}
 	}
 
+	httpResponseWriter.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", server.config().CacheMaxAge[publicSuffixHttpResponse.IsManagedBy]))
+
+	if publicSuffixHttpResponse.DNSStale {
+		httpResponseWriter.Header().Set("X-Cache", "STALE")
+	}
+
+	server.writeHttpResponse(httpResponseWriter, httpRequest, httpResponse)
+}
+
+func (server *Server) historyHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
 	domain := httpRequest.URL.Query().Get("domain")
 
+	if domain == "" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `domain`")
+		return
+	}
+
+	historyHttpResponse, err := publicsuffix.History(domain, server.config().LookupOptions)
+
+	if errors.Is(err, publicsuffix.ErrHistoryUnavailable) {
+		server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, err)
+		return
+	}
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain `%s`: %s", domain, err))
+		return
+	}
+
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(historyHttpResponse)
+}
+
+func (server *Server) suffixListDiffHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	from := httpRequest.URL.Query().Get("from")
+	to := httpRequest.URL.Query().Get("to")
+
+	if !snapshotDatePattern.MatchString(from) || !snapshotDatePattern.MatchString(to) {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameters `from` and `to`, expected dates like `2024-01`")
+		return
+	}
+
+	diffHttpResponse, err := publicsuffix.DiffSnapshots(from, to)
+
+	if errors.Is(err, publicsuffix.ErrSnapshotNotFound) {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusNotFound, ErrorIdNotFound, fmt.Sprintf("Unknown public suffix list snapshot: %s", err))
+		return
+	}
+
+	if err != nil {
+		server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, err)
+		return
+	}
+
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(diffHttpResponse)
+}
+
+func (server *Server) similarityHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	domain1 := httpRequest.URL.Query().Get("domain1")
+	domain2 := httpRequest.URL.Query().Get("domain2")
+
+	if domain1 == "" || domain2 == "" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameters `domain1` and `domain2`")
+		return
+	}
+
+	similarityHttpResponse, err := publicsuffix.CompareDomains(domain1, domain2, server.config().LookupOptions)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain: %s", err))
+		return
+	}
+
 	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
 
+	server.newJSONEncoder(httpResponseWriter).Encode(similarityHttpResponse)
+}
+
+func (server *Server) treeHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	domain := httpRequest.URL.Query().Get("domain")
+
 	if domain == "" {
-		httpResponseWriter.WriteHeader(http.StatusBadRequest)
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `domain`")
+		return
+	}
 
-		json.NewEncoder(httpResponseWriter).Encode(struct {
-			ErrorCode    int    `json:"errorCode"`
-			ErrorType    string `json:"errorType"`
-			ErrorMessage string `json:"errorMessage"`
-		}{
-			ErrorCode:    http.StatusBadRequest,
-			ErrorType:    http.StatusText(http.StatusBadRequest),
-			ErrorMessage: "Malformed URL query parameter `domain`",
-		})
+	treeHttpResponse, err := publicsuffix.Decompose(domain, server.config().LookupOptions)
 
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain `%s`: %s", domain, err))
 		return
 	}
 
-	json.NewEncoder(httpResponseWriter).Encode(publicSuffixHttpResponse(domain))
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(treeHttpResponse)
+}
+
+func (server *Server) tldHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	tld := strings.TrimPrefix(httpRequest.URL.Path, "/tld/")
+
+	if tld == "" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL path parameter `tld`")
+		return
+	}
+
+	tldHttpResponse, err := publicsuffix.LookupTLD(tld)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdTLDInvalid), fmt.Sprintf("Invalid TLD `%s`: %s", tld, err))
+		return
+	}
+
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(tldHttpResponse)
+}
+
+func (server *Server) healthHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	type HealthHttpResponse struct {
+		Status        string     `json:"status"`
+		LastCanaryRun *time.Time `json:"lastCanaryRun"`
+	}
+
+	healthHttpResponse := HealthHttpResponse{Status: "ok"}
+
+	canaryStatus.mutex.Lock()
+
+	if !canaryStatus.lastRunTime.IsZero() {
+		lastCanaryRun := canaryStatus.lastRunTime
+		healthHttpResponse.LastCanaryRun = &lastCanaryRun
+
+		if !canaryStatus.lastRunOk {
+			healthHttpResponse.Status = "degraded"
+		}
+	}
+
+	canaryStatus.mutex.Unlock()
+
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(healthHttpResponse)
+}
+
+func (server *Server) versionHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	type VersionHttpResponse struct {
+		Version       string     `json:"version"`
+		BuildTime     *time.Time `json:"buildTime"`
+		TLSMinVersion *string    `json:"tlsMinVersion"`
+		GoVersion     string     `json:"goVersion"`
+		GoOS          string     `json:"goOS"`
+		GoArch        string     `json:"goArch"`
+		CgoEnabled    bool       `json:"cgoEnabled"`
+	}
+
+	versionHttpResponse := VersionHttpResponse{
+		Version:    "devel",
+		GoVersion:  runtime.Version(),
+		GoOS:       runtime.GOOS,
+		GoArch:     runtime.GOARCH,
+		CgoEnabled: cgoEnabled,
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok && buildInfo.Main.Version != "" {
+		versionHttpResponse.Version = buildInfo.Main.Version
+	}
+
+	if buildTime, ok := buildTime(); ok {
+		versionHttpResponse.BuildTime = &buildTime
+	}
+
+	if server.config().TLSMinVersion != "" {
+		versionHttpResponse.TLSMinVersion = &server.config().TLSMinVersion
+	}
+
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(versionHttpResponse)
 }
 
-func faviconHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
-	favicon, _ := embededStaticFileSystem.ReadFile("static/favicon.ico")
+func (server *Server) faviconHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	var favicon []byte
+	var err error
+
+	if server.config().FaviconPath != "" {
+		if favicon, err = os.ReadFile(server.config().FaviconPath); err != nil {
+			logWarnf("falling back to embedded favicon: %s", err)
+		}
+	}
+
+	if favicon == nil {
+		if favicon, err = embededStaticFileSystem.ReadFile("static/favicon.ico"); err != nil {
+			server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, fmt.Errorf("reading embedded favicon failed: %w", err))
+			return
+		}
+	}
+
+	httpResponseWriter.Header().Set("Content-Type", "image/x-icon")
+	httpResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(favicon)))
+	httpResponseWriter.Header().Set("Cache-Control", "public, max-age=86400")
 
 	httpResponseWriter.Write(favicon)
 }
 
+func (server *Server) robotsTxtHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	var robotsTxt []byte
+	var err error
+
+	if server.config().RobotsTxtFile != "" {
+		if robotsTxt, err = os.ReadFile(server.config().RobotsTxtFile); err != nil {
+			logWarnf("falling back to embedded robots.txt: %s", err)
+		}
+	}
+
+	if robotsTxt == nil {
+		if robotsTxt, err = embededStaticFileSystem.ReadFile("static/robots.txt"); err != nil {
+			server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, fmt.Errorf("reading embedded robots.txt failed: %w", err))
+			return
+		}
+	}
+
+	httpResponseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	httpResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(robotsTxt)))
+	httpResponseWriter.Header().Set("Cache-Control", "public, max-age=86400")
+
+	httpResponseWriter.Write(robotsTxt)
+}
+
+// getEnv returns the value of key from the environment or the config file.
+//
 // See: https://pkg.go.dev/os#example-LookupEnv
 func getEnv(key string, fallback string) string {
-	value, exists := os.LookupEnv(key)
+	value, exists := lookupEnv(key)
 
 	if exists {
 		return value
@@ -122,22 +495,168 @@ func getEnv(key string, fallback string) string {
 	return fallback
 }
 
+// getEnvHTML returns the value of key for use in a template. It is escaped
+// unless ALLOW_HTML_IN_TEMPLATE=true, the fallback is trusted as is.
+func getEnvHTML(key string, fallback template.HTML) template.HTML {
+	value, exists := lookupEnv(key)
+
+	if !exists {
+		return fallback
+	}
+
+	if getEnv("ALLOW_HTML_IN_TEMPLATE", "false") == "true" {
+		return template.HTML(value)
+	}
+
+	return template.HTML(template.HTMLEscapeString(value))
+}
+
+func getEnvList(key string, fallback string) []string {
+	values := []string{}
+
+	for _, value := range strings.Split(getEnv(key, fallback), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(fallback)))
+
+	if err != nil {
+		log.Printf("ignoring invalid value for %s: %s", key, err)
+		return fallback
+	}
+
+	return value
+}
+
 func main() {
-	// Static
-	http.Handle("/static/", http.FileServer(http.FS(embededStaticFileSystem)))
-	http.HandleFunc("/favicon.ico", faviconHttpHandler)
+	if configFile := getEnv("CONFIG_FILE", ""); configFile != "" {
+		if err := loadConfigFile(configFile); err != nil {
+			log.Fatalf("loading config file failed: %s", err)
+		}
+	}
+
+	logLevel = parseLogLevel(getEnv("LOG_LEVEL", "info"))
 
-	// Dynamic
-	http.HandleFunc("/publicsuffix", publicSuffixHttpHandler)
+	if outboundSourceIP := getEnv("OUTBOUND_SOURCE_IP", ""); outboundSourceIP != "" {
+		if err := setOutboundSourceIP(outboundSourceIP); err != nil {
+			log.Fatalf("invalid OUTBOUND_SOURCE_IP: %s", err)
+		}
+	}
 
-	// Redirects
-	http.HandleFunc("/github", redirectHttpHandler("https://github.com/stefankuehnel/publicsuffix.stefan-dev.de"))
+	config := loadConfig()
 
-	// Templates
-	http.HandleFunc("/", indexHttpHandler)
+	// Slowing down production by accident would be hard to notice.
+	if (config.SimulatedLatency > 0 || config.SimulatedLatencyJitter > 0) && !config.DevMode {
+		log.Fatal("SIMULATED_LATENCY_MS and SIMULATED_LATENCY_JITTER_MS are only allowed with DEV_MODE=true")
+	}
+
+	if sentryDsn := getEnv("SENTRY_DSN", ""); sentryDsn != "" {
+		initSentry(sentryDsn)
+	}
+
+	startCanary(
+		parseCanaryDomains(getEnv("CANARY_DOMAINS", "example.com=ICANN,co.uk=ICANN,blogspot.com=PRIVATE_ENTITY")),
+		time.Duration(getEnvInt("CANARY_INTERVAL_SECONDS", 60))*time.Second,
+		config.LookupOptions,
+	)
+
+	if influxDBUrl := getEnv("INFLUXDB_URL", ""); influxDBUrl != "" {
+		startInfluxDBExport(influxDBUrl, getEnv("INFLUXDB_TOKEN", ""), time.Duration(getEnvInt("METRICS_FLUSH_INTERVAL_SECONDS", 10))*time.Second)
+	}
 
 	port := getEnv("PORT", "80")
 
-	log.Printf("listening on http://localhost:%s", port)
-	http.ListenAndServe(fmt.Sprintf(":%s", port), nil)
+	// An explicitly passed -port flag takes precedence over the PORT variable.
+	flag.StringVar(&port, "port", port, "port the HTTP server listens on")
+	flag.Parse()
+
+	flag.Visit(func(portFlag *flag.Flag) {
+		if envPort, exists := lookupEnv("PORT"); exists && portFlag.Name == "port" && envPort != port {
+			logWarnf("-port %s overrides PORT %s", port, envPort)
+		}
+	})
+
+	tlsCertificate, err := loadTLSCertificate(
+		getEnv("TLS_CERT_FILE", ""), getEnv("TLS_KEY_FILE", ""),
+		getEnv("TLS_CERT_PEM", ""), getEnv("TLS_KEY_PEM", ""),
+	)
+
+	if err != nil {
+		log.Fatalf("loading TLS certificate failed: %s", err)
+	}
+
+	var tlsMinVersion uint16
+
+	if tlsCertificate != nil {
+		tlsMinVersion, config.TLSMinVersion = parseTLSMinVersion(getEnv("TLS_MIN_VERSION", "1.2"))
+
+		startTLSCertificateMonitor(tlsCertificate.Leaf, getEnvInt("TLS_CERT_WARN_DAYS", 30))
+	}
+
+	server := NewServer(config)
+
+	if tlsCertificate == nil {
+		server.logStartup(fmt.Sprintf("http://localhost:%s", port), false)
+	} else {
+		server.logStartup(fmt.Sprintf("https://localhost:%s", port), true)
+	}
+
+	go server.reloadConfigOnSIGHUP()
+
+	httpServer := &http.Server{
+		Addr:        fmt.Sprintf(":%s", port),
+		Handler:     server,
+		IdleTimeout: time.Duration(getEnvInt("IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+	}
+
+	// Closes every connection after its response, IdleTimeout is moot then.
+	httpServer.SetKeepAlivesEnabled(getEnv("KEEPALIVE_DISABLED", "false") != "true")
+
+	go func() {
+		var err error
+
+		if tlsCertificate == nil {
+			log.Printf("listening on http://localhost:%s", port)
+			err = httpServer.ListenAndServe()
+		} else {
+			httpServer.TLSConfig = newTLSConfig(tlsMinVersion, *tlsCertificate)
+
+			log.Printf("listening on https://localhost:%s", port)
+			err = httpServer.ListenAndServeTLS("", "")
+		}
+
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	shutdownSignal, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	<-shutdownSignal.Done()
+
+	logInfof("shutting down")
+
+	drainTimeout := time.Duration(getEnvInt("DRAIN_TIMEOUT_SECONDS", 10)) * time.Second
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logErrorf("shutting down gracefully failed: %s", err)
+	}
+
+	// Hijacked connections and handlers outliving Shutdown are still running.
+	if inFlightRequests := server.drain(shutdownCtx); inFlightRequests > 0 {
+		logWarnf("Exiting with %d requests still in flight after draining for %s", inFlightRequests, drainTimeout)
+	}
+
+	// Events are sent asynchronously and would be lost on exit otherwise.
+	sentry.Flush(2 * time.Second)
 }
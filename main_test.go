@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+func TestFaviconHttpHandler(t *testing.T) {
+	server := NewServer(Config{})
+
+	httpResponseRecorder := httptest.NewRecorder()
+	server.faviconHttpHandler(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	if httpResponseRecorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", httpResponseRecorder.Code, http.StatusOK)
+	}
+
+	if contentType := httpResponseRecorder.Header().Get("Content-Type"); contentType != "image/x-icon" {
+		t.Errorf("Content-Type = %q, want %q", contentType, "image/x-icon")
+	}
+
+	if httpResponseRecorder.Body.Len() == 0 {
+		t.Error("body is empty")
+	}
+}
+
+func TestFaviconHttpHandlerFaviconPath(t *testing.T) {
+	faviconPath := filepath.Join(t.TempDir(), "favicon.ico")
+
+	if err := os.WriteFile(faviconPath, []byte("custom"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(Config{FaviconPath: faviconPath})
+
+	httpResponseRecorder := httptest.NewRecorder()
+	server.faviconHttpHandler(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+
+	if body := httpResponseRecorder.Body.String(); body != "custom" {
+		t.Errorf("body = %q, want the file at FAVICON_PATH", body)
+	}
+
+	if contentLength := httpResponseRecorder.Header().Get("Content-Length"); contentLength != "6" {
+		t.Errorf("Content-Length = %q, want %q", contentLength, "6")
+	}
+}
+
+func TestContextWriterInterruptsSlowTemplate(t *testing.T) {
+	slowTemplate := template.Must(template.New("slow").Funcs(template.FuncMap{
+		"slow": func() string {
+			time.Sleep(50 * time.Millisecond)
+			return "slow"
+		},
+	}).Parse("{{slow}} and more"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	contextWriter := &contextWriter{ctx: ctx}
+
+	if err := slowTemplate.Execute(contextWriter, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if contextWriter.buffer.Len() != 0 {
+		t.Errorf("wrote %q past the deadline", contextWriter.buffer.String())
+	}
+}
+
+func TestRenderTemplateTimeout(t *testing.T) {
+	config := loadConfig()
+	config.TemplateTimeout = time.Nanosecond
+	server := NewServer(config)
+
+	if _, err := server.renderTemplate(httptest.NewRequest(http.MethodGet, "/", nil), "index.html", server.newTemplateData()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("renderTemplate() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPublicSuffixHttpHandlerCancelled(t *testing.T) {
+	server := NewServer(loadConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	httpRequest := httptest.NewRequest(http.MethodGet, "/publicsuffix?domain=example.co.uk", nil).WithContext(ctx)
+	httpResponseRecorder := httptest.NewRecorder()
+
+	// The client goes away before the handler gets to the lookup.
+	cancel()
+	server.publicSuffixHttpHandler(httpResponseRecorder, httpRequest)
+
+	if httpResponseRecorder.Body.Len() != 0 {
+		t.Errorf("responded %q to a client that went away", httpResponseRecorder.Body.String())
+	}
+}
+
+// registryFunc is a publicsuffix.Registry that tests control.
+type registryFunc func(domain string) (string, bool)
+
+func (registry registryFunc) PublicSuffix(domain string) (string, bool) {
+	return registry(domain)
+}
+
+func TestPublicSuffixHttpHandlerWithRegistry(t *testing.T) {
+	config := loadConfig()
+	config.LookupOptions.Registry = registryFunc(func(domain string) (string, bool) {
+		return "corp.internal", true
+	})
+	server := NewServer(config)
+
+	httpResponseRecorder := httptest.NewRecorder()
+	server.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/publicsuffix?domain=www.example.corp.internal", nil))
+
+	if httpResponseRecorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", httpResponseRecorder.Code, http.StatusOK, httpResponseRecorder.Body.String())
+	}
+
+	var result publicsuffix.Result
+
+	if err := json.Unmarshal(httpResponseRecorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("body is no JSON: %s", err)
+	}
+
+	if result.PublicSuffix != "corp.internal" || result.IsManagedBy != publicsuffix.ManagedByIcann {
+		t.Errorf("result = %s managed by %s, want corp.internal managed by %s", result.PublicSuffix, result.IsManagedBy, publicsuffix.ManagedByIcann)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonErrorHttpResponseWriter rewrites the plain text 404 and 405 responses
+// produced by http.Error (and therefore http.NotFound, http.FileServer and
+// http.ServeMux) into the JSON error envelope used by the API.
+type jsonErrorHttpResponseWriter struct {
+	http.ResponseWriter
+	server      *Server
+	httpRequest *http.Request
+	intercepted bool
+}
+
+func (httpResponseWriter *jsonErrorHttpResponseWriter) WriteHeader(statusCode int) {
+	isPlainText := strings.HasPrefix(httpResponseWriter.Header().Get("Content-Type"), "text/plain")
+
+	if !isPlainText || (statusCode != http.StatusNotFound && statusCode != http.StatusMethodNotAllowed) {
+		httpResponseWriter.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	httpResponseWriter.intercepted = true
+
+	// The body is replaced, so a length set for the plain text one is stale.
+	httpResponseWriter.Header().Del("Content-Length")
+
+	errorId := ErrorIdNotFound
+	errorMessage := fmt.Sprintf("No resource found at path `%s`", httpResponseWriter.httpRequest.URL.Path)
+
+	if statusCode == http.StatusMethodNotAllowed {
+		errorId = ErrorIdMethodNotAllowed
+		errorMessage = fmt.Sprintf("Method `%s` is not allowed at path `%s`", httpResponseWriter.httpRequest.Method, httpResponseWriter.httpRequest.URL.Path)
+	}
+
+	if statusCode == http.StatusNotFound && acceptsHtml(httpResponseWriter.httpRequest) {
+		httpResponseWriter.server.writeNotFoundHtmlHttpResponse(httpResponseWriter.ResponseWriter, httpResponseWriter.httpRequest, errorMessage)
+		return
+	}
+
+	httpResponseWriter.server.writeErrorHttpResponse(httpResponseWriter.ResponseWriter, statusCode, errorId, errorMessage)
+}
+
+func (httpResponseWriter *jsonErrorHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.intercepted {
+		return len(data), nil
+	}
+
+	return httpResponseWriter.ResponseWriter.Write(data)
+}
+
+func (httpResponseWriter *jsonErrorHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+func (server *Server) jsonErrorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		next.ServeHTTP(&jsonErrorHttpResponseWriter{ResponseWriter: httpResponseWriter, server: server, httpRequest: httpRequest}, httpRequest)
+	})
+}
+
+// deprecationMiddleware marks responses for the given path prefixes as
+// deprecated, since deprecationDate if set. Once sunsetDate, if set, has
+// passed, these paths are answered with 410 Gone instead.
+//
+// See: https://datatracker.ietf.org/doc/draft-ietf-httpapi-deprecation-header/
+// See: https://www.rfc-editor.org/rfc/rfc8594
+func (server *Server) deprecationMiddleware(deprecatedPaths []string, deprecationLink string, deprecationDate time.Time, sunsetDate time.Time, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		for _, deprecatedPath := range deprecatedPaths {
+			if !strings.HasPrefix(httpRequest.URL.Path, deprecatedPath) {
+				continue
+			}
+
+			if deprecationDate.IsZero() {
+				httpResponseWriter.Header().Set("Deprecation", "true")
+			} else {
+				httpResponseWriter.Header().Set("Deprecation", deprecationDate.Format(http.TimeFormat))
+			}
+
+			if deprecationLink != "" {
+				httpResponseWriter.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", deprecationLink))
+			}
+
+			if !sunsetDate.IsZero() {
+				httpResponseWriter.Header().Set("Sunset", sunsetDate.Format(http.TimeFormat))
+
+				if !time.Now().Before(sunsetDate) {
+					server.writeErrorHttpResponse(httpResponseWriter, http.StatusGone, ErrorIdGone, fmt.Sprintf("Path `%s` was removed on %s", httpRequest.URL.Path, sunsetDate.Format(time.DateOnly)))
+					return
+				}
+			}
+
+			break
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
+
+// headHttpResponseWriter swallows the body of a HEAD response but counts its
+// bytes, so that the Content-Length matches the one of the equivalent GET.
+type headHttpResponseWriter struct {
+	http.ResponseWriter
+	statusCode    int
+	contentLength int
+	sniffedData   []byte
+}
+
+func (httpResponseWriter *headHttpResponseWriter) WriteHeader(statusCode int) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = statusCode
+	}
+}
+
+func (httpResponseWriter *headHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = http.StatusOK
+	}
+
+	httpResponseWriter.contentLength += len(data)
+
+	if missing := 512 - len(httpResponseWriter.sniffedData); missing > 0 {
+		if missing > len(data) {
+			missing = len(data)
+		}
+
+		httpResponseWriter.sniffedData = append(httpResponseWriter.sniffedData, data[:missing]...)
+	}
+
+	return len(data), nil
+}
+
+// FlushError keeps a streaming handler from sending the headers before the
+// Content-Length is known. There is no body to flush anyway.
+func (httpResponseWriter *headHttpResponseWriter) FlushError() error {
+	return nil
+}
+
+func (httpResponseWriter *headHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+// headAwareHandler answers HEAD requests by running the GET handler and
+// discarding its body while keeping all of its headers.
+func headAwareHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if httpRequest.Method != http.MethodHead {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		headHttpResponseWriter := &headHttpResponseWriter{ResponseWriter: httpResponseWriter}
+
+		next.ServeHTTP(headHttpResponseWriter, httpRequest)
+
+		if headHttpResponseWriter.statusCode == 0 {
+			headHttpResponseWriter.statusCode = http.StatusOK
+		}
+
+		// Mirrors the content sniffing net/http does on the first write of a GET.
+		if _, exists := httpResponseWriter.Header()["Content-Type"]; !exists && headHttpResponseWriter.contentLength > 0 {
+			httpResponseWriter.Header().Set("Content-Type", http.DetectContentType(headHttpResponseWriter.sniffedData))
+		}
+
+		if httpResponseWriter.Header().Get("Content-Length") == "" {
+			httpResponseWriter.Header().Set("Content-Length", strconv.Itoa(headHttpResponseWriter.contentLength))
+		}
+
+		httpResponseWriter.WriteHeader(headHttpResponseWriter.statusCode)
+	})
+}
+
+// securityHeadersMiddleware announces the server as serverHeader instead of
+// revealing the technology stack, or omits the Server header when empty.
+func securityHeadersMiddleware(serverHeader string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if serverHeader != "" {
+			httpResponseWriter.Header().Set("Server", serverHeader)
+		} else {
+			httpResponseWriter.Header().Del("Server")
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
+
+// noKeepAliveMiddleware closes the connection after the response to every
+// request, or only to those for which applies reports true, so that clients
+// of streamed responses read until EOF. All other responses keep the
+// connection alive, which net/http also announces with `Connection:
+// keep-alive` to HTTP/1.0 clients asking for it.
+//
+// See: https://pkg.go.dev/net/http#Server.SetKeepAlivesEnabled
+func noKeepAliveMiddleware(applies func(httpRequest *http.Request) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if applies == nil || applies(httpRequest) {
+			httpResponseWriter.Header().Set("Connection", "close")
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
+
+// latencyMiddleware delays every request by latency plus or minus up to
+// jitter, e.g. to load test clients against realistic response times. It is
+// only allowed with DEV_MODE=true.
+func latencyMiddleware(latency time.Duration, jitter time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		delay := latency
+
+		if jitter > 0 {
+			delay += time.Duration(rand.Int64N(int64(2*jitter+1))) - jitter
+		}
+
+		select {
+		case <-time.After(max(delay, 0)):
+		case <-httpRequest.Context().Done():
+			return
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// v2FieldRenames maps the fields of the v1 `/publicsuffix` response to their
+// name in the v2 response format. Fields not listed here keep their name.
+var v2FieldRenames = map[string]string{
+	"isManagedBy": "managedBy",
+}
+
+type MigrateHttpResponse struct {
+	V1 publicsuffix.Result `json:"v1"`
+	V2 map[string]any      `json:"v2"`
+}
+
+// migrateV1ToV2 converts a v1 response into the v2 response format by
+// renaming its fields, which keeps both in sync as fields are added.
+func migrateV1ToV2(result publicsuffix.Result) (map[string]any, error) {
+	data, err := json.Marshal(result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	v1 := map[string]any{}
+
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return nil, err
+	}
+
+	v2 := make(map[string]any, len(v1))
+
+	for field, value := range v1 {
+		if renamed, ok := v2FieldRenames[field]; ok {
+			field = renamed
+		}
+
+		v2[field] = value
+	}
+
+	return v2, nil
+}
+
+// migrateHttpHandler returns the v1 and v2 response of a domain side by
+// side, so that client developers can compare both formats.
+func (server *Server) migrateHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	if from := httpRequest.URL.Query().Get("from"); from != "v1" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `from`, expected `v1`")
+		return
+	}
+
+	domain := httpRequest.URL.Query().Get("domain")
+
+	if domain == "" {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `domain`")
+		return
+	}
+
+	result, err := publicsuffix.Lookup(domain, server.config().LookupOptions)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, lookupErrorId(err, ErrorIdDomainInvalid), fmt.Sprintf("Invalid domain `%s`: %s", domain, err))
+		return
+	}
+
+	v2, err := migrateV1ToV2(result)
+
+	if err != nil {
+		server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, err)
+		return
+	}
+
+	httpResponseWriter.Header().Add("Content-Type", "application/json; charset=utf-8")
+
+	server.newJSONEncoder(httpResponseWriter).Encode(MigrateHttpResponse{V1: result, V2: v2})
+}
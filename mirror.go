@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// mirroredHeader marks replayed requests so that a mirror pointing back at
+// this instance does not replay them again.
+const mirroredHeader = "X-Mirrored"
+
+type MirrorJob struct {
+	query  url.Values
+	result publicsuffix.Result
+}
+
+// Mirror replays lookups against a secondary endpoint, e.g. a canary of a new
+// version, and logs whenever its results diverge. Jobs are handled by a fixed
+// number of workers and dropped when the queue is full so that the primary
+// response path never waits on the mirror.
+type Mirror struct {
+	endpoint   string
+	jobs       chan MirrorJob
+	httpClient *http.Client
+}
+
+func startMirror(endpoint string, workers int) *Mirror {
+	mirror := &Mirror{
+		endpoint:   endpoint,
+		jobs:       make(chan MirrorJob, 100),
+		httpClient: newOutboundHttpClient(10 * time.Second),
+	}
+
+	for worker := 0; worker < workers; worker++ {
+		go func() {
+			for job := range mirror.jobs {
+				mirror.replay(job)
+			}
+		}()
+	}
+
+	return mirror
+}
+
+func (mirror *Mirror) enqueue(query url.Values, result publicsuffix.Result) {
+	select {
+	case mirror.jobs <- MirrorJob{query: query, result: result}:
+	default:
+		logDebugf("mirror queue is full, dropping lookup for %s", result.Domain)
+	}
+}
+
+func (mirror *Mirror) replay(job MirrorJob) {
+	httpRequest, err := http.NewRequest(http.MethodGet, mirror.endpoint+"?"+job.query.Encode(), nil)
+
+	if err != nil {
+		logWarnf("mirror request for %s failed: %s", job.result.Domain, err)
+		return
+	}
+
+	httpRequest.Header.Set(mirroredHeader, "true")
+
+	httpResponse, err := mirror.httpClient.Do(withClientTrace(httpRequest, "mirror"))
+
+	if err != nil {
+		logWarnf("mirror request for %s failed: %s", job.result.Domain, err)
+		return
+	}
+
+	defer httpResponse.Body.Close()
+
+	mirroredResult := publicsuffix.Result{}
+
+	if httpResponse.StatusCode != http.StatusOK {
+		logInfof("mirror diverged for %s: status %d", job.result.Domain, httpResponse.StatusCode)
+		return
+	}
+
+	if err := json.NewDecoder(httpResponse.Body).Decode(&mirroredResult); err != nil {
+		logWarnf("mirror response for %s is malformed: %s", job.result.Domain, err)
+		return
+	}
+
+	if mirroredResult.PublicSuffix != job.result.PublicSuffix || mirroredResult.IsManagedBy != job.result.IsManagedBy {
+		logInfof(
+			"mirror diverged for %s: publicSuffix %s (mirror %s), isManagedBy %s (mirror %s)",
+			job.result.Domain,
+			job.result.PublicSuffix, mirroredResult.PublicSuffix,
+			job.result.IsManagedBy, mirroredResult.IsManagedBy,
+		)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// outboundTransport carries the requests of the server to other services,
+// i.e. the reputation API, the mirror endpoint, the proxy upstream, the TLD
+// route backends and InfluxDB.
+var outboundTransport http.RoundTripper = http.DefaultTransport
+
+// newOutboundHttpClient returns a client sending its requests over
+// outboundTransport.
+func newOutboundHttpClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: outboundTransport, Timeout: timeout}
+}
+
+// setOutboundSourceIP makes outbound connections leave from sourceIP, e.g. to
+// pick the network interface of a multi-homed server. sourceIP must belong to
+// one of the interfaces of the host.
+func setOutboundSourceIP(sourceIP string) error {
+	ip := net.ParseIP(sourceIP)
+
+	if ip == nil {
+		return fmt.Errorf("%q is no IP address", sourceIP)
+	}
+
+	interfaceAddrs, err := net.InterfaceAddrs()
+
+	if err != nil {
+		return err
+	}
+
+	for _, interfaceAddr := range interfaceAddrs {
+		if ipNet, isIPNet := interfaceAddr.(*net.IPNet); isIPNet && ipNet.IP.Equal(ip) {
+			// The same settings as http.DefaultTransport, except for LocalAddr.
+			dialer := &net.Dialer{
+				LocalAddr: &net.TCPAddr{IP: ip},
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}
+
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.DialContext = dialer.DialContext
+
+			outboundTransport = transport
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s belongs to no network interface", ip)
+}
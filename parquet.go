@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+const parquetContentType = "application/vnd.apache.parquet"
+
+// ParquetRow flattens a publicsuffix.Result into a Parquet row. The column
+// names are the JSON field names of the batch response. There are no
+// reputation or diagnostics columns, as both are only added to single
+// lookups.
+//
+// See: https://parquet.apache.org/docs/file-format/
+type ParquetRow struct {
+	Domain               string             `parquet:"domain"`
+	PublicSuffix         string             `parquet:"publicSuffix"`
+	IsManagedBy          string             `parquet:"isManagedBy"`
+	IsManagedByLabel     string             `parquet:"isManagedByLabel,optional"`
+	LabelCount           int                `parquet:"labelCount"`
+	Labels               []string           `parquet:"labels,list"`
+	Normalized           bool               `parquet:"normalized"`
+	NetSuffix            string             `parquet:"netSuffix"`
+	Context              string             `parquet:"context"`
+	RegistrarSuffix      string             `parquet:"registrarSuffix"`
+	PublicSuffixUnicode  string             `parquet:"publicSuffixUnicode"`
+	IsPublicSuffix       bool               `parquet:"isPublicSuffix"`
+	IsAtRegistrableLevel bool               `parquet:"isAtRegistrableLevel"`
+	ETLDPlusTwo          string             `parquet:"etldPlusTwo,optional"`
+	TLDIntroducedYear    int                `parquet:"tldIntroducedYear"`
+	TLDDelegationDate    string             `parquet:"tldDelegationDate,optional"`
+	Entropy              float64            `parquet:"entropy"`
+	IDNAValid            bool               `parquet:"idnaValid"`
+	IDNAError            string             `parquet:"idnaError,optional"`
+	AllCandidates        []ParquetCandidate `parquet:"allCandidates,list"`
+	Explanation          string             `parquet:"explanation,optional"`
+	DNSResolvable        *bool              `parquet:"dnsResolvable,optional"`
+	DNSAddresses         []string           `parquet:"dnsAddresses,list"`
+	DNSSECEnabled        *bool              `parquet:"dnssecEnabled,optional"`
+}
+
+type ParquetCandidate struct {
+	PublicSuffix string `parquet:"publicSuffix"`
+	IsManagedBy  string `parquet:"isManagedBy"`
+}
+
+func newParquetRow(result publicsuffix.Result) ParquetRow {
+	parquetRow := ParquetRow{
+		Domain:               result.Domain,
+		PublicSuffix:         result.PublicSuffix,
+		IsManagedBy:          result.IsManagedBy,
+		IsManagedByLabel:     result.IsManagedByLabel,
+		LabelCount:           result.LabelCount,
+		Labels:               result.Labels,
+		Normalized:           result.Normalized,
+		NetSuffix:            result.NetSuffix,
+		Context:              result.Context,
+		RegistrarSuffix:      result.RegistrarSuffix,
+		PublicSuffixUnicode:  result.PublicSuffixUnicode,
+		IsPublicSuffix:       result.IsPublicSuffix,
+		IsAtRegistrableLevel: result.IsAtRegistrableLevel,
+		ETLDPlusTwo:          result.ETLDPlusTwo,
+		TLDIntroducedYear:    result.TLDIntroducedYear,
+		TLDDelegationDate:    result.TLDDelegationDate,
+		Entropy:              result.Entropy,
+		IDNAValid:            result.IDNAValid,
+		IDNAError:            result.IDNAError,
+		Explanation:          result.Explanation,
+		DNSResolvable:        result.DNSResolvable,
+		DNSAddresses:         result.DNSAddresses,
+		DNSSECEnabled:        result.DNSSECEnabled,
+	}
+
+	for _, candidate := range result.AllCandidates {
+		parquetRow.AllCandidates = append(parquetRow.AllCandidates, ParquetCandidate(candidate))
+	}
+
+	return parquetRow
+}
+
+func acceptsParquet(httpRequest *http.Request) bool {
+	return strings.Contains(httpRequest.Header.Get("Accept"), parquetContentType)
+}
+
+// writeParquetBatchHttpResponse writes every chunk of the batch as a row
+// group as soon as it is looked up.
+func (server *Server) writeParquetBatchHttpResponse(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, domains []string) {
+	writer := parquet.NewGenericWriter[ParquetRow](httpResponseWriter)
+
+	header := http.Header{}
+	header.Set("Content-Type", parquetContentType)
+	header.Set("Content-Disposition", `attachment; filename="results.parquet"`)
+
+	ok := server.streamBatchLookup(httpResponseWriter, httpRequest, domains, header, func(results []publicsuffix.Result) error {
+		localize(httpResponseWriter, results)
+
+		rows := make([]ParquetRow, len(results))
+
+		for index, result := range results {
+			rows[index] = newParquetRow(result)
+		}
+
+		if _, err := writer.Write(rows); err != nil {
+			return err
+		}
+
+		return writer.Flush()
+	})
+
+	if !ok {
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		logErrorf("Writing Parquet footer failed: %s", err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetBatchIsManagedByLabel(t *testing.T) {
+	httpResponse, body := integrationRequest(t, http.MethodPost, "/publicsuffix/batch?locale=de", `{"domains":["www.example.co.uk"]}`, http.Header{
+		"Content-Type": {"application/json"},
+		"Accept":       {parquetContentType},
+	})
+
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", httpResponse.StatusCode, http.StatusOK, body)
+	}
+
+	rows, err := parquet.Read[ParquetRow](bytes.NewReader(body), int64(len(body)))
+
+	if err != nil {
+		t.Fatalf("body is no Parquet file: %s", err)
+	}
+
+	if len(rows) != 1 || rows[0].IsManagedByLabel != "Von der ICANN verwaltet" {
+		t.Errorf("rows = %+v, want one labeled in German", rows)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// proxyHandler forwards percentage percent of the lookups to upstream, e.g. an
+// instance with a newer public suffix list, and serves the rest with next.
+// Lookups are assigned by a hash of their domain, so that a domain is always
+// answered by the same instance.
+func (server *Server) proxyHandler(upstream *url.URL, percentage int, next http.Handler) http.Handler {
+	reverseProxy := httputil.NewSingleHostReverseProxy(upstream)
+	reverseProxy.Transport = outboundTransport
+
+	reverseProxy.ModifyResponse = func(httpResponse *http.Response) error {
+		domain := httpResponse.Request.URL.Query().Get("domain")
+
+		if httpResponse.StatusCode != http.StatusOK {
+			logDebugf("upstream diverged for %s: status %d", domain, httpResponse.StatusCode)
+			return nil
+		}
+
+		body, err := io.ReadAll(httpResponse.Body)
+		httpResponse.Body.Close()
+
+		if err != nil {
+			return err
+		}
+
+		httpResponse.Body = io.NopCloser(bytes.NewReader(body))
+		upstreamResult := publicsuffix.Result{}
+
+		if err := json.Unmarshal(body, &upstreamResult); err != nil {
+			logDebugf("upstream response for %s is malformed: %s", domain, err)
+			return nil
+		}
+
+		localResult, err := publicsuffix.Lookup(domain, server.config().LookupOptions)
+
+		if err != nil || localResult.PublicSuffix != upstreamResult.PublicSuffix || localResult.IsManagedBy != upstreamResult.IsManagedBy {
+			logDebugf(
+				"upstream diverged for %s: publicSuffix %s (upstream %s), isManagedBy %s (upstream %s)",
+				domain,
+				localResult.PublicSuffix, upstreamResult.PublicSuffix,
+				localResult.IsManagedBy, upstreamResult.IsManagedBy,
+			)
+		}
+
+		return nil
+	}
+
+	reverseProxy.ErrorHandler = func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, err error) {
+		logWarnf("proxying lookup for %s to %s failed: %s", httpRequest.URL.Query().Get("domain"), upstream, err)
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadGateway, ErrorIdUpstreamUnavailable, "Upstream is unavailable")
+	}
+
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		domain := publicsuffix.NormalizeDomain(httpRequest.URL.Query().Get("domain"))
+
+		if domain == "" {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		hash := fnv.New32a()
+		hash.Write([]byte(domain))
+
+		if int(hash.Sum32()%100) >= percentage {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		reverseProxy.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
@@ -0,0 +1,107 @@
+package publicsuffix
+
+import "strings"
+
+// The lists a matched rule comes from, see Diagnostics.ListSource.
+const (
+	ListSourceIcann   = "ICANN"
+	ListSourcePrivate = "PRIVATE"
+	ListSourceCustom  = "CUSTOM"
+	ListSourceNone    = "NONE"
+)
+
+// diagnosticsProbeLabel is looked up below a suffix to tell whether a wildcard
+// rule covers it. It cannot be the label of any rule, as those are no
+// Punycode labels with a hyphen at their third and fourth position.
+const diagnosticsProbeLabel = "xx--publicsuffix-probe"
+
+// Diagnostics describes how a lookup came to its result, for support engineers
+// who cannot reproduce it locally.
+type Diagnostics struct {
+	NormalizedInput string `json:"normalizedInput"`
+
+	// MatchedRule is the rule of the Public Suffix List that matched, e.g.
+	// `co.uk`, `*.ck` or `!www.ck`, or the implicit rule `*` for TLDs missing
+	// from it.
+	MatchedRule string `json:"matchedRule"`
+
+	// ListSource is the section of the list MatchedRule is in, or CUSTOM when
+	// Options.Registry replaces the list.
+	ListSource string `json:"listSource"`
+
+	ProcessingSteps []string `json:"processingSteps"`
+}
+
+// diagnose returns the diagnostics of the lookup of domain, which has
+// publicSuffix.
+func diagnose(registry Registry, domain string, publicSuffix string, isManagedBy string, opts Options) *Diagnostics {
+	diagnostics := &Diagnostics{
+		NormalizedInput: domain,
+		MatchedRule:     matchedRule(registry, domain, publicSuffix, isManagedBy),
+		ListSource:      ListSourceNone,
+	}
+
+	switch {
+	case opts.Registry != nil:
+		diagnostics.ListSource = ListSourceCustom
+	case isManagedBy == ManagedByIcann:
+		diagnostics.ListSource = ListSourceIcann
+	case isManagedBy == ManagedByPrivateEntity:
+		diagnostics.ListSource = ListSourcePrivate
+	}
+
+	if !opts.Strict {
+		diagnostics.ProcessingSteps = append(diagnostics.ProcessingSteps, "normalize", "toASCII")
+	}
+
+	diagnostics.ProcessingSteps = append(diagnostics.ProcessingSteps, "validate", "matchRule")
+
+	for _, optionalStep := range []struct {
+		enabled bool
+		name    string
+	}{
+		{opts.AllCandidates, "allCandidates"},
+		{opts.Explain, "explain"},
+		{opts.ResolveDNS, "resolveDNS"},
+		{opts.CheckDNSSEC, "checkDNSSEC"},
+	} {
+		if optionalStep.enabled {
+			diagnostics.ProcessingSteps = append(diagnostics.ProcessingSteps, optionalStep.name)
+		}
+	}
+
+	return diagnostics
+}
+
+// matchedRule reconstructs the rule that made publicSuffix the public suffix
+// of domain, as the registry only tells the suffix itself. A suffix is
+// covered by a wildcard rule when any other label in place of its first one
+// is a suffix as well. A domain one label longer than its suffix that
+// escapes such a wildcard does so by an exception rule.
+//
+// See: https://github.com/publicsuffix/list/wiki/Format#algorithm
+func matchedRule(registry Registry, domain string, publicSuffix string, isManagedBy string) string {
+	if isManagedBy == ManagedByNone {
+		return "*"
+	}
+
+	isSuffix := func(candidate string) bool {
+		candidatePublicSuffix, _ := registry.PublicSuffix(candidate)
+
+		return candidatePublicSuffix == candidate
+	}
+
+	if domain != publicSuffix {
+		registrableDomain := domain[strings.LastIndex(strings.TrimSuffix(domain, "."+publicSuffix), ".")+1:]
+
+		if isSuffix(diagnosticsProbeLabel + "." + publicSuffix) {
+			return "!" + registrableDomain
+		}
+	}
+
+	if _, parent, found := strings.Cut(publicSuffix, "."); found && isSuffix(diagnosticsProbeLabel+"."+parent) {
+		return "*." + parent
+	}
+
+	return publicSuffix
+}
@@ -0,0 +1,143 @@
+package publicsuffix
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Sections of the public suffix list.
+const (
+	SectionIcann   = "ICANN"
+	SectionPrivate = "PRIVATE"
+)
+
+// ErrSnapshotNotFound is returned by DiffSnapshots when there is no snapshot
+// of or before a date.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Entry is a rule of the public suffix list, e.g. `*.ck` or `!www.ck`, along
+// with the section it is listed in.
+type Entry struct {
+	Rule    string
+	Section string
+}
+
+// SectionChange is a rule that moved between the sections of the public
+// suffix list.
+type SectionChange struct {
+	Suffix string `json:"suffix"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// DiffResult lists the rules added, removed and moved between two versions
+// of the public suffix list, each sorted by rule. From and To are the dates
+// of the snapshots compared by DiffSnapshots.
+type DiffResult struct {
+	From    string          `json:"from,omitempty"`
+	To      string          `json:"to,omitempty"`
+	Added   []string        `json:"added"`
+	Removed []string        `json:"removed"`
+	Changed []SectionChange `json:"changed"`
+}
+
+// Diff compares the entries of a public suffix list a with those of a newer
+// one b.
+func Diff(a, b []Entry) DiffResult {
+	sections := make(map[string]string, len(a))
+
+	for _, entry := range a {
+		sections[entry.Rule] = entry.Section
+	}
+
+	diffResult := DiffResult{Added: []string{}, Removed: []string{}, Changed: []SectionChange{}}
+
+	for _, entry := range b {
+		section, exists := sections[entry.Rule]
+
+		if !exists {
+			diffResult.Added = append(diffResult.Added, entry.Rule)
+			continue
+		}
+
+		// Whatever is left in sections afterwards was removed.
+		delete(sections, entry.Rule)
+
+		if section != entry.Section {
+			diffResult.Changed = append(diffResult.Changed, SectionChange{Suffix: entry.Rule, From: section, To: entry.Section})
+		}
+	}
+
+	for rule := range sections {
+		diffResult.Removed = append(diffResult.Removed, rule)
+	}
+
+	sort.Strings(diffResult.Added)
+	sort.Strings(diffResult.Removed)
+	sort.Slice(diffResult.Changed, func(i, j int) bool {
+		return diffResult.Changed[i].Suffix < diffResult.Changed[j].Suffix
+	})
+
+	return diffResult
+}
+
+// snapshotAt returns the latest snapshot taken in or before the period date
+// is the prefix of, e.g. the one of 2023-07-09 for `2024-01` when there is
+// none of January 2024, since that is the list that was current then.
+func snapshotAt(snapshots []historySnapshot, date string) (historySnapshot, error) {
+	for index := len(snapshots) - 1; index >= 0; index-- {
+		snapshotDate := snapshots[index].date
+
+		if snapshotDate[:min(len(date), len(snapshotDate))] <= date {
+			return snapshots[index], nil
+		}
+	}
+
+	return historySnapshot{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, date)
+}
+
+// snapshotEntries returns the rules of snapshot as entries.
+func snapshotEntries(snapshot historySnapshot) []Entry {
+	entries := make([]Entry, 0, len(snapshot.rules))
+
+	for rule, icann := range snapshot.rules {
+		entry := Entry{Rule: rule, Section: SectionPrivate}
+
+		if icann {
+			entry.Section = SectionIcann
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// DiffSnapshots compares the snapshots of the public suffix list current at
+// the dates from and to, given as prefixes like `2024-03`.
+func DiffSnapshots(from string, to string) (DiffResult, error) {
+	snapshots, err := historySnapshots()
+
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("%w: %s", ErrHistoryUnavailable, err)
+	}
+
+	fromSnapshot, err := snapshotAt(snapshots, from)
+
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	toSnapshot, err := snapshotAt(snapshots, to)
+
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	diffResult := Diff(snapshotEntries(fromSnapshot), snapshotEntries(toSnapshot))
+	diffResult.From = fromSnapshot.date
+	diffResult.To = toSnapshot.date
+
+	return diffResult, nil
+}
@@ -0,0 +1,92 @@
+package publicsuffix
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := []Entry{
+		{"com", SectionIcann},
+		{"old.example", SectionIcann},
+		{"github.io", SectionIcann},
+		{"*.ck", SectionIcann},
+	}
+
+	b := []Entry{
+		{"*.ck", SectionIcann},
+		{"github.io", SectionPrivate},
+		{"new.example", SectionPrivate},
+		{"com", SectionIcann},
+		{"app", SectionIcann},
+	}
+
+	want := DiffResult{
+		Added:   []string{"app", "new.example"},
+		Removed: []string{"old.example"},
+		Changed: []SectionChange{{Suffix: "github.io", From: SectionIcann, To: SectionPrivate}},
+	}
+
+	if diffResult := Diff(a, b); !reflect.DeepEqual(diffResult, want) {
+		t.Errorf("Diff() = %+v, want %+v", diffResult, want)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	entries := []Entry{{"com", SectionIcann}, {"github.io", SectionPrivate}}
+	diffResult := Diff(entries, entries)
+
+	if len(diffResult.Added) != 0 || len(diffResult.Removed) != 0 || len(diffResult.Changed) != 0 {
+		t.Errorf("Diff() = %+v, want no changes", diffResult)
+	}
+}
+
+func TestSnapshotAt(t *testing.T) {
+	snapshots := []historySnapshot{{date: "2023-07-09"}, {date: "2024-03-01"}, {date: "2024-06-14"}}
+
+	tests := []struct {
+		date         string
+		snapshotDate string
+		err          error
+	}{
+		{"2024-01", "2023-07-09", nil},
+		{"2024-03", "2024-03-01", nil},
+		{"2024-04", "2024-03-01", nil},
+		{"2024-03-01", "2024-03-01", nil},
+		{"2024-02-29", "2023-07-09", nil},
+		{"2024", "2024-06-14", nil},
+		{"2030-01", "2024-06-14", nil},
+		{"2023-06", "", ErrSnapshotNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			snapshot, err := snapshotAt(snapshots, test.date)
+
+			if !errors.Is(err, test.err) || snapshot.date != test.snapshotDate {
+				t.Errorf("snapshotAt(%q) = %q, %v, want %q, %v", test.date, snapshot.date, err, test.snapshotDate, test.err)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	diffResult, err := DiffSnapshots("2024-01", "2024-04")
+
+	if err != nil {
+		t.Fatalf("DiffSnapshots() error = %v", err)
+	}
+
+	if diffResult.From != "2023-07-09" || diffResult.To != "2024-03-01" {
+		t.Errorf("DiffSnapshots() compared %s to %s, want 2023-07-09 to 2024-03-01", diffResult.From, diffResult.To)
+	}
+
+	if len(diffResult.Added)+len(diffResult.Removed)+len(diffResult.Changed) == 0 {
+		t.Error("DiffSnapshots() found no changes")
+	}
+
+	if _, err := DiffSnapshots("2017-01", "2024-04"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("DiffSnapshots() before the first snapshot = %v, want %v", err, ErrSnapshotNotFound)
+	}
+}
@@ -0,0 +1,123 @@
+package publicsuffix
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultDNSTimeout  = 3 * time.Second
+	DefaultDNSCacheTTL = 5 * time.Minute
+
+	maxDNSCacheEntries = 10000
+)
+
+type dnsCacheEntry struct {
+	addresses []string
+	expiresAt time.Time
+
+	// Until staleUntil, an expired entry is still answered from while it is
+	// being refreshed in the background.
+	staleUntil time.Time
+	refreshing bool
+}
+
+var dnsCacheHits atomic.Uint64
+
+// DNSCacheHits returns how many DNS resolutions were answered from the cache.
+func DNSCacheHits() uint64 {
+	return dnsCacheHits.Load()
+}
+
+// dnsCache is kept apart from the suffix lookup so that resolving a domain
+// never slows down callers that only ask for its public suffix.
+var dnsCache = struct {
+	mutex   sync.Mutex
+	entries map[string]*dnsCacheEntry
+}{entries: map[string]*dnsCacheEntry{}}
+
+// resolveDNS returns the addresses of domain, or nil when it does not resolve
+// within timeout or before ctx is done. Once a cached answer has expired, it
+// is still returned for staleGrace, marked as stale, while a fresh one is
+// resolved in the background.
+//
+// See: https://www.rfc-editor.org/rfc/rfc5861#section-3
+func resolveDNS(ctx context.Context, domain string, timeout time.Duration, staleGrace time.Duration) ([]string, bool) {
+	now := time.Now()
+
+	dnsCache.mutex.Lock()
+	entry, exists := dnsCache.entries[domain]
+
+	if exists && now.Before(entry.expiresAt) {
+		dnsCache.mutex.Unlock()
+		dnsCacheHits.Add(1)
+
+		return entry.addresses, false
+	}
+
+	if exists && now.Before(entry.staleUntil) {
+		refresh := !entry.refreshing
+		entry.refreshing = true
+		dnsCache.mutex.Unlock()
+		dnsCacheHits.Add(1)
+
+		// The refresh must outlive the request that triggered it.
+		if refresh {
+			go resolveAndCacheDNS(context.Background(), domain, timeout, staleGrace)
+		}
+
+		return entry.addresses, true
+	}
+
+	dnsCache.mutex.Unlock()
+
+	return resolveAndCacheDNS(ctx, domain, timeout, staleGrace), false
+}
+
+func resolveAndCacheDNS(ctx context.Context, domain string, timeout time.Duration, staleGrace time.Duration) []string {
+	if timeout <= 0 {
+		timeout = DefaultDNSTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, domain)
+
+	dnsCache.mutex.Lock()
+	defer dnsCache.mutex.Unlock()
+
+	if err != nil {
+		var dnsError *net.DNSError
+
+		// Only cache definite answers, a timeout may well succeed next time.
+		if !errors.As(err, &dnsError) || !dnsError.IsNotFound {
+			if entry, exists := dnsCache.entries[domain]; exists {
+				entry.refreshing = false
+			}
+
+			return nil
+		}
+
+		addresses = nil
+	}
+
+	now := time.Now()
+
+	if len(dnsCache.entries) >= maxDNSCacheEntries {
+		for cachedDomain, cachedEntry := range dnsCache.entries {
+			if now.After(cachedEntry.staleUntil) {
+				delete(dnsCache.entries, cachedDomain)
+			}
+		}
+	}
+
+	expiresAt := now.Add(DefaultDNSCacheTTL)
+	dnsCache.entries[domain] = &dnsCacheEntry{addresses: addresses, expiresAt: expiresAt, staleUntil: expiresAt.Add(staleGrace)}
+
+	return addresses
+}
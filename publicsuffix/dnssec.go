@@ -0,0 +1,66 @@
+package publicsuffix
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const resolvConfPath = "/etc/resolv.conf"
+
+var loadResolvConf = sync.OnceValues(func() (*dns.ClientConfig, error) {
+	return dns.ClientConfigFromFile(resolvConfPath)
+})
+
+// checkDNSSEC reports whether the zone of domain is signed, i.e. its parent
+// zone has a DS record for it that the resolver authenticated, as told by
+// the AD flag. It is false when the resolver fails to answer within timeout,
+// which makes it only as trustworthy as the resolver and the way to it.
+//
+// See: https://www.rfc-editor.org/rfc/rfc4035#section-3.2.3
+func checkDNSSEC(ctx context.Context, registry Registry, domain string, timeout time.Duration) bool {
+	clientConfig, err := loadResolvConf()
+
+	if err != nil || len(clientConfig.Servers) == 0 {
+		return false
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultDNSTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Subdomains rarely are zones of their own, so ask for the delegation of
+	// the registrable domain.
+	zone, err := effectiveTLDPlusOne(registry, domain)
+
+	if err != nil {
+		zone = domain
+	}
+
+	message := new(dns.Msg)
+	message.SetQuestion(dns.Fqdn(zone), dns.TypeDS)
+	message.AuthenticatedData = true
+	message.SetEdns0(4096, true)
+
+	client := &dns.Client{}
+	answer, _, err := client.ExchangeContext(ctx, message, net.JoinHostPort(clientConfig.Servers[0], clientConfig.Port))
+
+	if err != nil || answer.Rcode != dns.RcodeSuccess || !answer.AuthenticatedData {
+		return false
+	}
+
+	// An authenticated answer without DS records proves the zone unsigned.
+	for _, record := range answer.Answer {
+		if _, isDS := record.(*dns.DS); isDS {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package publicsuffix
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	MaxDomainLength      = 253
+	DefaultMaxLabelCount = 127
+)
+
+var (
+	ErrDomainEmpty         = errors.New("domain is empty")
+	ErrDomainTooLong       = errors.New("domain is too long")
+	ErrDomainTooManyLabels = errors.New("domain has too many labels")
+	ErrDomainEmptyLabel    = errors.New("domain contains an empty label")
+
+	ErrDomainUnderscoreLabel = errors.New("domain contains a label with an underscore")
+)
+
+// NormalizeDomain trims and lowercases domain. Pasted URLs such as
+// `https://example.com/path?utm_source=email#section` are reduced to their
+// host, which is also detected without a scheme, e.g. in `example.com/path`,
+// `//example.com` or `example.com:80`.
+func NormalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+
+	if host, ok := hostOfURL(domain); ok {
+		return host
+	}
+
+	return domain
+}
+
+// hostOfURL returns the host of rawURL, if it looks like a URL rather than a
+// bare domain. As net/url only recognizes the host after a scheme, one is
+// added when missing.
+//
+// See: https://pkg.go.dev/net/url#URL.Hostname
+func hostOfURL(rawURL string) (string, bool) {
+	switch {
+	case strings.Contains(rawURL, "://"):
+	case strings.HasPrefix(rawURL, "//"):
+		rawURL = "https:" + rawURL
+	case strings.ContainsAny(rawURL, "/?#:@"):
+		rawURL = "https://" + rawURL
+	default:
+		return "", false
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+
+	if err != nil || parsedURL.Hostname() == "" {
+		return "", false
+	}
+
+	return parsedURL.Hostname(), true
+}
+
+// ValidateDomain reports whether the already normalized domain is acceptable
+// for a lookup. The returned error wraps one of the ErrDomain* errors.
+//
+// See: https://www.rfc-editor.org/rfc/rfc1035#section-2.3.4
+func ValidateDomain(domain string, opts Options) error {
+	if domain == "" {
+		return ErrDomainEmpty
+	}
+
+	if len(domain) > MaxDomainLength {
+		return fmt.Errorf("%w: the maximum is %d characters", ErrDomainTooLong, MaxDomainLength)
+	}
+
+	maxLabelCount := opts.MaxLabelCount
+
+	if maxLabelCount <= 0 {
+		maxLabelCount = DefaultMaxLabelCount
+	}
+
+	labels := strings.Split(domain, ".")
+
+	if len(labels) > maxLabelCount {
+		return fmt.Errorf("%w: the maximum is %d labels", ErrDomainTooManyLabels, maxLabelCount)
+	}
+
+	for _, label := range labels {
+		if label == "" {
+			return fmt.Errorf("%w: check for leading, trailing or consecutive dots", ErrDomainEmptyLabel)
+		}
+
+		if !strings.Contains(label, "_") {
+			continue
+		}
+
+		if !opts.AllowUnderscore {
+			return fmt.Errorf("%w `%s`: underscores are not allowed in host names", ErrDomainUnderscoreLabel, label)
+		}
+
+		// A leading underscore marks service labels such as `_dmarc`.
+		if strings.HasSuffix(label, "_") {
+			return fmt.Errorf("%w `%s`: labels must not end with an underscore", ErrDomainUnderscoreLabel, label)
+		}
+	}
+
+	return nil
+}
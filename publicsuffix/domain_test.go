@@ -0,0 +1,84 @@
+package publicsuffix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain string
+		err    error
+	}{
+		{"valid", "www.example.com", nil},
+		{"empty", "", ErrDomainEmpty},
+		{"consecutive dots", "a..b.com", ErrDomainEmptyLabel},
+		{"trailing dot", "a.", ErrDomainEmptyLabel},
+		{"leading dot", ".a.com", ErrDomainEmptyLabel},
+		{"127 labels", strings.Repeat("a.", 126) + "a", nil},
+		// 128 labels take at least 255 characters, so the length limit
+		// rejects them before the label count limit does.
+		{"128 labels", strings.Repeat("a.", 127) + "a", ErrDomainTooLong},
+		{"254 characters", strings.Repeat("a", 250) + ".com", ErrDomainTooLong},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateDomain(test.domain, Options{})
+
+			if !errors.Is(err, test.err) {
+				t.Errorf("ValidateDomain(%q) = %v, want %v", test.domain, err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidateDomainMaxLabelCount(t *testing.T) {
+	if err := ValidateDomain("a.b.c.d", Options{MaxLabelCount: 3}); !errors.Is(err, ErrDomainTooManyLabels) {
+		t.Errorf("ValidateDomain with MaxLabelCount 3 = %v, want %v", err, ErrDomainTooManyLabels)
+	}
+
+	if err := ValidateDomain("a.b.c", Options{MaxLabelCount: 3}); err != nil {
+		t.Errorf("ValidateDomain with MaxLabelCount 3 = %v, want nil", err)
+	}
+}
+
+func TestLookupRejectsEmptyLabels(t *testing.T) {
+	for _, domain := range []string{"a..b.com", "a."} {
+		if _, err := Lookup(domain, Options{}); !errors.Is(err, ErrDomainEmptyLabel) {
+			t.Errorf("Lookup(%q) = %v, want %v", domain, err, ErrDomainEmptyLabel)
+		}
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		input  string
+		domain string
+	}{
+		{"example.com/path", "example.com"},
+		{"//example.com", "example.com"},
+		{"example.com:80", "example.com"},
+		{"", ""},
+		{"https://example.com/path?utm_source=email#section", "example.com"},
+		{" WWW.Example.COM ", "www.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			if domain := NormalizeDomain(test.input); domain != test.domain {
+				t.Errorf("NormalizeDomain(%q) = %q, want %q", test.input, domain, test.domain)
+			}
+		})
+	}
+}
+
+func TestHostOfURLIgnoresBareDomains(t *testing.T) {
+	for _, domain := range []string{"", "example.com"} {
+		if host, ok := hostOfURL(domain); ok {
+			t.Errorf("hostOfURL(%q) = %q, want no URL", domain, host)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package publicsuffix
+
+import (
+	"math"
+	"strings"
+)
+
+// registrableLabel returns the label directly in front of publicSuffix, e.g.
+// `google` for `www.google.co.uk`, or an empty string when there is none.
+func registrableLabel(domain string, publicSuffix string) string {
+	if domain == publicSuffix || !strings.HasSuffix(domain, "."+publicSuffix) {
+		return ""
+	}
+
+	labels := strings.Split(strings.TrimSuffix(domain, "."+publicSuffix), ".")
+
+	return labels[len(labels)-1]
+}
+
+// shannonEntropy returns the Shannon entropy of the characters in value in
+// bits per character.
+//
+// See: https://en.wikipedia.org/wiki/Entropy_(information_theory)
+func shannonEntropy(value string) float64 {
+	runes := []rune(value)
+	frequencies := map[rune]int{}
+
+	for _, character := range runes {
+		frequencies[character]++
+	}
+
+	entropy := 0.0
+
+	for _, frequency := range frequencies {
+		probability := float64(frequency) / float64(len(runes))
+		entropy -= probability * math.Log2(probability)
+	}
+
+	return entropy
+}
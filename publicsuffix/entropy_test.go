@@ -0,0 +1,68 @@
+package publicsuffix
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShannonEntropy(t *testing.T) {
+	tests := []struct {
+		value   string
+		entropy float64
+	}{
+		{"", 0},
+		{"aaaa", 0},
+		{"ab", 1},
+		{"abcd", 2},
+		{"google", 2.0/3*math.Log2(3) + 1.0/3*math.Log2(6)},
+		{"xkj3bq", math.Log2(6)},
+	}
+
+	for _, test := range tests {
+		if entropy := shannonEntropy(test.value); math.Abs(entropy-test.entropy) > 1e-9 {
+			t.Errorf("shannonEntropy(%q) = %f, want %f", test.value, entropy, test.entropy)
+		}
+	}
+}
+
+func TestLookupEntropy(t *testing.T) {
+	google, err := Lookup("www.google.co.uk", Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dga, err := Lookup("xkj3bq.com", Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the registrable label counts, not `www` or the public suffix. The
+	// sum over a map is not always added up in the same order.
+	if math.Abs(google.Entropy-shannonEntropy("google")) > 1e-9 {
+		t.Errorf("Entropy of www.google.co.uk = %f, want the one of `google`", google.Entropy)
+	}
+
+	if dga.Entropy <= google.Entropy {
+		t.Errorf("Entropy of xkj3bq.com = %f, want more than %f of google.co.uk", dga.Entropy, google.Entropy)
+	}
+}
+
+func TestRegistrableLabel(t *testing.T) {
+	tests := []struct {
+		domain       string
+		publicSuffix string
+		label        string
+	}{
+		{"www.google.co.uk", "co.uk", "google"},
+		{"example.com", "com", "example"},
+		{"com", "com", ""},
+	}
+
+	for _, test := range tests {
+		if label := registrableLabel(test.domain, test.publicSuffix); label != test.label {
+			t.Errorf("registrableLabel(%q, %q) = %q, want %q", test.domain, test.publicSuffix, label, test.label)
+		}
+	}
+}
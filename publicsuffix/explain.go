@@ -0,0 +1,75 @@
+package publicsuffix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// explanationTemplates explain a public suffix by its isManagedBy value and
+// whether it consists of a single label or several. They are formatted with
+// the domain, the public suffix and the level of the public suffix.
+var explanationTemplates = map[string][2]string{
+	ManagedByIcann: {
+		"The domain '%[1]s' has public suffix '%[2]s' because '%[2]s' is an ICANN-managed %[3]s domain.",
+		"The domain '%[1]s' has public suffix '%[2]s' because '%[2]s' is an ICANN-managed %[3]s domain, under which its registry hands out names like under a top-level domain.",
+	},
+	ManagedByPrivateEntity: {
+		"The domain '%[1]s' has public suffix '%[2]s' because the operator of '%[2]s' listed it in the private section of the Public Suffix List.",
+		"The domain '%[1]s' has public suffix '%[2]s' because the operator of the %[3]s domain '%[2]s' listed it in the private section of the Public Suffix List, so that the names of its customers are kept apart like separately registered domains.",
+	},
+	ManagedByNone: {
+		"The domain '%[1]s' has public suffix '%[2]s' because '%[2]s' is not on the Public Suffix List, which then treats the last label as the public suffix.",
+		"The domain '%[1]s' has public suffix '%[2]s' because no rule of the Public Suffix List matches the %[3]s domain '%[2]s'.",
+	},
+}
+
+// explain describes in plain English why domain has publicSuffix.
+func explain(domain string, publicSuffix string, isManagedBy string) string {
+	labelCount := strings.Count(publicSuffix, ".") + 1
+	template := explanationTemplates[isManagedBy][0]
+
+	if labelCount > 1 {
+		template = explanationTemplates[isManagedBy][1]
+	}
+
+	explanation := fmt.Sprintf(template, domain, publicSuffix, domainLevel(labelCount))
+
+	if domain == publicSuffix {
+		explanation += " Being a public suffix itself, the domain cannot be registered."
+	}
+
+	return explanation
+}
+
+// domainLevel names the level of a domain with labelCount labels, e.g.
+// `second-level` for `co.uk`.
+func domainLevel(labelCount int) string {
+	switch labelCount {
+	case 1:
+		return "top-level"
+	case 2:
+		return "second-level"
+	case 3:
+		return "third-level"
+	}
+
+	return ordinal(labelCount) + "-level"
+}
+
+// ordinal returns the English ordinal of n, e.g. `21st`, `22nd`, `23rd` and
+// `11th`.
+func ordinal(n int) string {
+	suffix := "th"
+
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+
+	return fmt.Sprintf("%d%s", n, suffix)
+}
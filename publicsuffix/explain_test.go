@@ -0,0 +1,29 @@
+package publicsuffix
+
+import "testing"
+
+func TestDomainLevel(t *testing.T) {
+	tests := []struct {
+		labelCount int
+		level      string
+	}{
+		{1, "top-level"},
+		{2, "second-level"},
+		{3, "third-level"},
+		{4, "4th-level"},
+		{11, "11th-level"},
+		{12, "12th-level"},
+		{13, "13th-level"},
+		{21, "21st-level"},
+		{22, "22nd-level"},
+		{23, "23rd-level"},
+		{101, "101st-level"},
+		{111, "111th-level"},
+	}
+
+	for _, test := range tests {
+		if level := domainLevel(test.labelCount); level != test.level {
+			t.Errorf("domainLevel(%d) = %q, want %q", test.labelCount, level, test.level)
+		}
+	}
+}
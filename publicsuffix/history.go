@@ -0,0 +1,195 @@
+package publicsuffix
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate go run ./internal/genhistory -source goproxy -o history -since 2018-01-01
+
+//go:embed history/*.dat
+var embeddedHistory embed.FS
+
+// ErrHistoryUnavailable is returned by History when the embedded snapshots
+// cannot be read.
+var ErrHistoryUnavailable = errors.New("history is unavailable")
+
+// HistoryRecord is the public suffix of a domain in a snapshot of the public
+// suffix list.
+type HistoryRecord struct {
+	Date         string `json:"date"`
+	PublicSuffix string `json:"publicSuffix"`
+	IsManagedBy  string `json:"isManagedBy"`
+}
+
+type historyRule struct {
+	rule  string
+	icann bool
+}
+
+// historySnapshot is a snapshot of the public suffix list, with whether each
+// of its rules is listed in the ICANN section.
+type historySnapshot struct {
+	date  string
+	rules map[string]bool
+}
+
+// parseHistorySnapshot parses a snapshot written by genhistory, which lists
+// every rule in its ASCII form.
+//
+// See: https://github.com/publicsuffix/list/wiki/Format
+func parseHistorySnapshot(date string, data []byte) (historySnapshot, error) {
+	snapshot := historySnapshot{date: date, rules: map[string]bool{}}
+	icann := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.Contains(line, "===BEGIN ICANN DOMAINS==="):
+			icann = true
+		case strings.Contains(line, "===BEGIN PRIVATE DOMAINS==="):
+			icann = false
+		}
+
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		snapshot.rules[line] = icann
+	}
+
+	return snapshot, scanner.Err()
+}
+
+// historySnapshots parses the embedded snapshots once, oldest first, as
+// fs.ReadDir sorts them by their file name, that is by date.
+var historySnapshots = sync.OnceValues(func() ([]historySnapshot, error) {
+	directoryEntries, err := fs.ReadDir(embeddedHistory, "history")
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []historySnapshot{}
+
+	for _, directoryEntry := range directoryEntries {
+		date := strings.TrimSuffix(directoryEntry.Name(), ".dat")
+
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", directoryEntry.Name(), err)
+		}
+
+		data, err := embeddedHistory.ReadFile("history/" + directoryEntry.Name())
+
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err := parseHistorySnapshot(date, data)
+
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", directoryEntry.Name(), err)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+})
+
+// History normalizes and validates domain and returns its public suffix in
+// every snapshot of the public suffix list, oldest first.
+func History(domain string, opts Options) ([]HistoryRecord, error) {
+	result, err := Lookup(domain, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	domain = result.Domain
+
+	snapshots, err := historySnapshots()
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrHistoryUnavailable, err)
+	}
+
+	// Only rules that could match the domain are of interest, that is every
+	// suffix of it as normal, wildcard or exception rule.
+	labels := strings.Split(domain, ".")
+	candidates := []string{}
+
+	for index := range labels {
+		suffix := strings.Join(labels[index:], ".")
+		candidates = append(candidates, suffix, "*."+suffix, "!"+suffix)
+	}
+
+	records := make([]HistoryRecord, len(snapshots))
+
+	for index, snapshot := range snapshots {
+		rules := []historyRule{}
+
+		for _, candidate := range candidates {
+			if icann, exists := snapshot.rules[candidate]; exists {
+				rules = append(rules, historyRule{rule: candidate, icann: icann})
+			}
+		}
+
+		publicSuffix, isManagedBy := matchHistoryRules(domain, rules)
+		records[index] = HistoryRecord{Date: snapshot.date, PublicSuffix: publicSuffix, IsManagedBy: isManagedBy}
+	}
+
+	return records, nil
+}
+
+// matchHistoryRules applies the public suffix list algorithm to the rules
+// matching domain.
+//
+// See: https://github.com/publicsuffix/list/wiki/Format#algorithm
+func matchHistoryRules(domain string, rules []historyRule) (string, string) {
+	labels := strings.Split(domain, ".")
+
+	// Without a matching rule the implicit `*` rule applies.
+	publicSuffix, icann, matchedLabels := labels[len(labels)-1], false, 0
+
+	for _, rule := range rules {
+		if exception, isException := strings.CutPrefix(rule.rule, "!"); isException {
+			_, publicSuffix, _ = strings.Cut(exception, ".")
+			icann = rule.icann
+
+			break
+		}
+
+		suffix, isWildcard := strings.CutPrefix(rule.rule, "*.")
+		ruleLabels := strings.Count(suffix, ".") + 1
+
+		if isWildcard {
+			if ruleLabels >= len(labels) {
+				continue
+			}
+
+			ruleLabels++
+			suffix = labels[len(labels)-ruleLabels] + "." + suffix
+		}
+
+		if ruleLabels > matchedLabels {
+			publicSuffix, icann, matchedLabels = suffix, rule.icann, ruleLabels
+		}
+	}
+
+	if icann {
+		return publicSuffix, ManagedByIcann
+	} else if strings.IndexByte(publicSuffix, '.') >= 0 {
+		return publicSuffix, ManagedByPrivateEntity
+	}
+
+	return publicSuffix, ManagedByNone
+}
@@ -0,0 +1,51 @@
+package publicsuffix
+
+import "testing"
+
+func TestHistory(t *testing.T) {
+	records, err := History("www.example.co.uk", Options{})
+
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+
+	if len(records) == 0 {
+		t.Fatal("History() returned no snapshots")
+	}
+
+	for index, record := range records {
+		if index > 0 && record.Date <= records[index-1].Date {
+			t.Errorf("History() snapshot %s follows %s, want oldest first", record.Date, records[index-1].Date)
+		}
+
+		if record.PublicSuffix != "co.uk" || record.IsManagedBy != ManagedByIcann {
+			t.Errorf("History() in %s = %s managed by %s, want co.uk managed by %s", record.Date, record.PublicSuffix, record.IsManagedBy, ManagedByIcann)
+		}
+	}
+}
+
+func TestMatchHistoryRules(t *testing.T) {
+	tests := []struct {
+		name         string
+		domain       string
+		rules        []historyRule
+		publicSuffix string
+		isManagedBy  string
+	}{
+		{"no rule", "www.example.test", nil, "test", ManagedByNone},
+		{"normal rule", "www.example.co.uk", []historyRule{{"uk", true}, {"co.uk", true}}, "co.uk", ManagedByIcann},
+		{"wildcard rule", "www.example.ck", []historyRule{{"*.ck", true}}, "example.ck", ManagedByIcann},
+		{"exception rule", "www.ck", []historyRule{{"*.ck", true}, {"!www.ck", true}}, "ck", ManagedByIcann},
+		{"private rule", "foo.github.io", []historyRule{{"io", true}, {"github.io", false}}, "github.io", ManagedByPrivateEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			publicSuffix, isManagedBy := matchHistoryRules(test.domain, test.rules)
+
+			if publicSuffix != test.publicSuffix || isManagedBy != test.isManagedBy {
+				t.Errorf("matchHistoryRules(%q) = %s, %s, want %s, %s", test.domain, publicSuffix, isManagedBy, test.publicSuffix, test.isManagedBy)
+			}
+		})
+	}
+}
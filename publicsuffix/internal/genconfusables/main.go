@@ -0,0 +1,142 @@
+// Command genconfusables regenerates confusables.json from the Unicode
+// confusables data of UTS #39, given as URL or file in the format of
+// confusables.txt. Only the characters allowed in internationalized domain
+// names are kept, as no others can occur in a valid domain.
+//
+// Usage:
+//
+//	go run ./internal/genconfusables -i https://www.unicode.org/Public/security/latest/confusables.txt -o confusables.json
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// read returns the content of input, a URL or a file.
+func read(input string) (string, error) {
+	if !strings.HasPrefix(input, "https://") && !strings.HasPrefix(input, "http://") {
+		data, err := os.ReadFile(input)
+
+		return string(data), err
+	}
+
+	httpResponse, err := http.Get(input)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", input, httpResponse.Status)
+	}
+
+	data, err := io.ReadAll(httpResponse.Body)
+
+	return string(data), err
+}
+
+// parseCodePoints parses hexadecimal code points separated by spaces, e.g.
+// `0072 006E` for `rn`.
+func parseCodePoints(value string) (string, error) {
+	builder := strings.Builder{}
+
+	for _, field := range strings.Fields(value) {
+		codePoint, err := strconv.ParseUint(field, 16, 32)
+
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteRune(rune(codePoint))
+	}
+
+	return builder.String(), nil
+}
+
+// idnAllowed reports whether character may occur in an internationalized
+// domain name. Combining marks are only allowed after another character.
+//
+// See: https://www.rfc-editor.org/rfc/rfc5892
+func idnAllowed(character string) bool {
+	if _, err := idna.Registration.ToASCII(character); err == nil {
+		return true
+	}
+
+	_, err := idna.Registration.ToASCII("a" + character)
+
+	return err == nil
+}
+
+func main() {
+	input := flag.String("i", "https://www.unicode.org/Public/security/latest/confusables.txt", "URL or file of the confusables data")
+	output := flag.String("o", "confusables.json", "file to write")
+	flag.Parse()
+
+	data, err := read(*input)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	confusables := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(strings.TrimPrefix(data, "\ufeff")))
+
+	// E.g. `0430 ;	0061 ;	MA	# ( а → a ) CYRILLIC SMALL LETTER A → LATIN SMALL LETTER A`.
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Split(line, ";")
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		character, err := parseCodePoints(fields[0])
+
+		if err != nil {
+			log.Fatalf("%q: %s", scanner.Text(), err)
+		}
+
+		prototype, err := parseCodePoints(fields[1])
+
+		if err != nil {
+			log.Fatalf("%q: %s", scanner.Text(), err)
+		}
+
+		// Domains are lowercase, so `0` is to be mistaken for `o` rather than
+		// the `O` of its prototype.
+		prototype = strings.ToLower(prototype)
+
+		if prototype != character && idnAllowed(character) {
+			confusables[character] = prototype
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	encoded, err := json.MarshalIndent(confusables, "", "  ")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, append(encoded, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("wrote %d confusables", len(confusables))
+}
@@ -0,0 +1,334 @@
+// Command genhistory regenerates the snapshots of the public suffix list in
+// the history directory, one file `YYYY-MM-DD.dat` per snapshot in the format
+// of the list itself, with every rule in its ASCII form.
+//
+// The snapshots are taken either quarterly from the GitHub repository of the
+// list, or from the releases of github.com/weppos/publicsuffix-go on the Go
+// module proxy, each of which compiles in the list of its date.
+//
+// Usage:
+//
+//	go run ./internal/genhistory -source github -o history -since 2020-01-01
+//	go run ./internal/genhistory -source goproxy -o history -since 2018-01-01
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	commitsUrl = "https://api.github.com/repos/publicsuffix/list/commits?path=public_suffix_list.dat&per_page=1&until=%s"
+	listUrl    = "https://raw.githubusercontent.com/publicsuffix/list/%s/public_suffix_list.dat"
+
+	modulePath  = "github.com/weppos/publicsuffix-go"
+	versionsUrl = "https://proxy.golang.org/" + modulePath + "/@v/list"
+	zipUrl      = "https://proxy.golang.org/" + modulePath + "/@v/%s.zip"
+)
+
+var (
+	// E.g. `const ListVersion = "PSL version 7ef638 (Mon Mar  2 12:22:01 2026)"`,
+	// named defaultListVersion in older releases.
+	listVersionPattern = regexp.MustCompile(`ListVersion = "(PSL version \w+ \((.+)\))"`)
+
+	// E.g. `{2, "bd", 1, false}`, that is the type (1 for normal, 2 for
+	// wildcard and 3 for exception rules), the rule, its number of labels and
+	// whether it is listed in the private section.
+	rulePattern = regexp.MustCompile(`\{([123]), "([^"]+)", \d+, (true|false)\}`)
+)
+
+type Rule struct {
+	Rule  string
+	Icann bool
+}
+
+type Snapshot struct {
+	Date   time.Time
+	Source string
+	Rules  []Rule
+}
+
+func fetch(url string) ([]byte, error) {
+	httpResponse, err := http.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, httpResponse.Status)
+	}
+
+	return io.ReadAll(httpResponse.Body)
+}
+
+// revisionAt returns the last commit that changed the list before date.
+func revisionAt(date time.Time) (string, error) {
+	body, err := fetch(fmt.Sprintf(commitsUrl, date.Format(time.RFC3339)))
+
+	if err != nil {
+		return "", err
+	}
+
+	commits := []struct {
+		Sha string `json:"sha"`
+	}{}
+
+	if err := json.Unmarshal(body, &commits); err != nil {
+		return "", err
+	}
+
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no revision before %s", date.Format(time.DateOnly))
+	}
+
+	return commits[0].Sha, nil
+}
+
+// parseRules parses the list into rules in their ASCII form, which is the
+// form publicsuffix.Lookup matches domains in.
+//
+// See: https://github.com/publicsuffix/list/wiki/Format
+func parseRules(list string) ([]Rule, error) {
+	rules := []Rule{}
+	icann := false
+	scanner := bufio.NewScanner(strings.NewReader(list))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.Contains(line, "===BEGIN ICANN DOMAINS==="):
+			icann = true
+		case strings.Contains(line, "===BEGIN PRIVATE DOMAINS==="):
+			icann = false
+		}
+
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		rule := strings.Fields(line)[0]
+		prefix := ""
+
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			prefix, rule = "!", rule[1:]
+		case strings.HasPrefix(rule, "*."):
+			prefix, rule = "*.", rule[2:]
+		}
+
+		asciiRule, err := idna.Lookup.ToASCII(rule)
+
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", line, err)
+		}
+
+		rules = append(rules, Rule{Rule: prefix + asciiRule, Icann: icann})
+	}
+
+	return rules, scanner.Err()
+}
+
+// githubSnapshots takes a snapshot of the list at the start of every quarter
+// since since.
+func githubSnapshots(since time.Time) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+
+	for date := since; date.Before(time.Now()); date = date.AddDate(0, 3, 0) {
+		revision, err := revisionAt(date)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := fetch(fmt.Sprintf(listUrl, revision))
+
+		if err != nil {
+			return nil, err
+		}
+
+		rules, err := parseRules(string(list))
+
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, Snapshot{Date: date, Source: "revision " + revision + " of github.com/publicsuffix/list", Rules: rules})
+	}
+
+	return snapshots, nil
+}
+
+// goproxySnapshot reads the list compiled into a release of
+// github.com/weppos/publicsuffix-go, whose rules are in their ASCII form
+// already.
+func goproxySnapshot(version string) (Snapshot, error) {
+	body, err := fetch(fmt.Sprintf(zipUrl, version))
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	file, err := archive.Open(modulePath + "@" + version + "/publicsuffix/rules.go")
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	defer file.Close()
+
+	source, err := io.ReadAll(file)
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	listVersion := listVersionPattern.FindSubmatch(source)
+
+	if listVersion == nil {
+		return Snapshot{}, fmt.Errorf("%s: no list version", version)
+	}
+
+	date, err := time.Parse("Mon Jan _2 15:04:05 2006", string(listVersion[2]))
+
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", version, err)
+	}
+
+	rules := []Rule{}
+
+	for _, match := range rulePattern.FindAllSubmatch(source, -1) {
+		rule := string(match[2])
+
+		switch string(match[1]) {
+		case "2":
+			rule = "*." + rule
+		case "3":
+			rule = "!" + rule
+		}
+
+		rules = append(rules, Rule{Rule: rule, Icann: string(match[3]) == "false"})
+	}
+
+	return Snapshot{Date: date, Source: string(listVersion[1]) + " of " + modulePath + "@" + version, Rules: rules}, nil
+}
+
+// goproxySnapshots takes a snapshot of the list of every release since since.
+// Releases without a change of the list result in the same snapshot.
+func goproxySnapshots(since time.Time) ([]Snapshot, error) {
+	body, err := fetch(versionsUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+
+	for _, version := range strings.Fields(string(body)) {
+		snapshot, err := goproxySnapshot(version)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !snapshot.Date.Before(since) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Date.Before(snapshots[j].Date)
+	})
+
+	return snapshots, nil
+}
+
+// writeSnapshot writes the rules of snapshot in the format of the list, so
+// that the ICANN and private sections are told apart the same way.
+func writeSnapshot(directory string, snapshot Snapshot) error {
+	var buffer bytes.Buffer
+
+	fmt.Fprintf(&buffer, "// %s\n", snapshot.Source)
+
+	for _, section := range []string{"ICANN", "PRIVATE"} {
+		fmt.Fprintf(&buffer, "\n// ===BEGIN %s DOMAINS===\n", section)
+
+		for _, rule := range snapshot.Rules {
+			if rule.Icann == (section == "ICANN") {
+				fmt.Fprintln(&buffer, rule.Rule)
+			}
+		}
+
+		fmt.Fprintf(&buffer, "// ===END %s DOMAINS===\n", section)
+	}
+
+	return os.WriteFile(filepath.Join(directory, snapshot.Date.Format(time.DateOnly)+".dat"), buffer.Bytes(), 0o644)
+}
+
+func main() {
+	output := flag.String("o", "history", "directory to write the snapshots to")
+	source := flag.String("source", "github", "where to take the snapshots from, github or goproxy")
+	since := flag.String("since", "2020-01-01", "date of the first snapshot")
+	flag.Parse()
+
+	date, err := time.Parse(time.DateOnly, *since)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var snapshots []Snapshot
+
+	switch *source {
+	case "github":
+		snapshots, err = githubSnapshots(date)
+	case "goproxy":
+		snapshots, err = goproxySnapshots(date)
+	default:
+		err = fmt.Errorf("unknown source %q", *source)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.RemoveAll(*output); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.MkdirAll(*output, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, snapshot := range snapshots {
+		if err := writeSnapshot(*output, snapshot); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("wrote %d rules of %s as of %s", len(snapshot.Rules), snapshot.Source, snapshot.Date.Format(time.DateOnly))
+	}
+}
@@ -0,0 +1,241 @@
+// Command gentlds regenerates tlds.json either from the IANA Root Zone
+// Database or from the TLD map of github.com/zmap/zlint on the Go module
+// proxy, which is compiled from the former and ICANN's gTLD registry data.
+//
+// Usage:
+//
+//	go run ./internal/gentlds -source iana -o tlds.json
+//	go run ./internal/gentlds -source zlint -o tlds.json
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	rootZoneDatabaseUrl = "https://www.iana.org/domains/root/db"
+
+	zlintModulePath = "github.com/zmap/zlint/v3"
+	zlintZipUrl     = "https://proxy.golang.org/" + zlintModulePath + "/@v/%s.zip"
+
+	// zlintPlaceholderDate is listed instead of the delegation date of the
+	// country-code TLDs and of the TLDs predating ICANN.
+	zlintPlaceholderDate = "1985-01-01"
+)
+
+var (
+	tldRowPattern           = regexp.MustCompile(`href="/domains/root/db/([^"]+)\.html">[^<]*</a></span></td>\s*<td>([^<]+)</td>`)
+	registrationDatePattern = regexp.MustCompile(`Registration date\s*</b>\s*((\d{4})-\d{2}-\d{2})`)
+
+	// E.g. `GTLD: "app", DelegationDate: "2015-07-02", RemovalDate: "",`.
+	zlintTLDPattern = regexp.MustCompile(`GTLD:\s*"([^"]+)",\s*DelegationDate:\s*"([^"]*)",\s*RemovalDate:\s*"([^"]*)"`)
+
+	// The TLDs the IANA Root Zone Database lists as other than generic or
+	// country-code, which the zlint map does not tell apart.
+	//
+	// See: https://www.iana.org/domains/root/db
+	zlintTLDTypes = map[string]string{
+		"arpa":   "infrastructure",
+		"biz":    "generic-restricted",
+		"name":   "generic-restricted",
+		"pro":    "generic-restricted",
+		"aero":   "sponsored",
+		"asia":   "sponsored",
+		"cat":    "sponsored",
+		"coop":   "sponsored",
+		"edu":    "sponsored",
+		"gov":    "sponsored",
+		"int":    "sponsored",
+		"jobs":   "sponsored",
+		"mil":    "sponsored",
+		"museum": "sponsored",
+		"post":   "sponsored",
+		"tel":    "sponsored",
+		"travel": "sponsored",
+		"xxx":    "sponsored",
+	}
+
+	// The TLDs that were indeed delegated on zlintPlaceholderDate.
+	//
+	// See: https://www.rfc-editor.org/rfc/rfc920
+	zlintFirstTLDs = map[string]bool{"arpa": true, "com": true, "edu": true, "gov": true, "mil": true, "net": true, "org": true}
+)
+
+type TLD struct {
+	TLD            string `json:"tld"`
+	Type           string `json:"type"`
+	IntroducedYear int    `json:"introducedYear,omitempty"`
+	DelegationDate string `json:"delegationDate,omitempty"`
+}
+
+func fetch(url string) (string, error) {
+	httpResponse, err := http.Get(url)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", url, httpResponse.Status)
+	}
+
+	body, err := io.ReadAll(httpResponse.Body)
+
+	return string(body), err
+}
+
+// zlintTLDs reads the TLDs currently delegated from the TLD map of a zlint
+// release. Its placeholder date is only kept for the TLDs that were delegated
+// on it, the country-code TLDs are told apart by their name.
+func zlintTLDs(version string) ([]TLD, error) {
+	body, err := fetch(fmt.Sprintf(zlintZipUrl, version))
+
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader([]byte(body)), int64(len(body)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := archive.Open(zlintModulePath + "@" + version + "/util/gtld_map.go")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	source, err := io.ReadAll(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tlds := []TLD{}
+
+	for _, match := range zlintTLDPattern.FindAllStringSubmatch(string(source), -1) {
+		name, delegationDate, removalDate := match[1], match[2], match[3]
+
+		if removalDate != "" {
+			continue
+		}
+
+		tld := TLD{TLD: name, Type: "generic"}
+
+		if tldType, exists := zlintTLDTypes[name]; exists {
+			tld.Type = tldType
+		} else if len(name) == 2 || (strings.HasPrefix(name, "xn--") && delegationDate == zlintPlaceholderDate) {
+			tld.Type = "country-code"
+		}
+
+		if delegationDate != zlintPlaceholderDate || zlintFirstTLDs[name] {
+			tld.DelegationDate = delegationDate
+			tld.IntroducedYear, _ = strconv.Atoi(delegationDate[:4])
+		}
+
+		tlds = append(tlds, tld)
+	}
+
+	sort.Slice(tlds, func(i, j int) bool {
+		return tlds[i].TLD < tlds[j].TLD
+	})
+
+	return tlds, nil
+}
+
+// ianaTLDs reads the TLDs from the IANA Root Zone Database.
+func ianaTLDs() ([]TLD, error) {
+	rootZoneDatabase, err := fetch(rootZoneDatabaseUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tlds := []TLD{}
+
+	for _, match := range tldRowPattern.FindAllStringSubmatch(rootZoneDatabase, -1) {
+		tlds = append(tlds, TLD{TLD: match[1], Type: match[2]})
+	}
+
+	// The introduction year is only listed on the page of each TLD.
+	semaphore := make(chan struct{}, 8)
+	waitGroup := sync.WaitGroup{}
+
+	for index := range tlds {
+		waitGroup.Add(1)
+
+		go func(tld *TLD) {
+			defer waitGroup.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			page, err := fetch(fmt.Sprintf("%s/%s.html", rootZoneDatabaseUrl, tld.TLD))
+
+			if err != nil {
+				log.Printf("skipping introduction year of %s: %s", tld.TLD, err)
+				return
+			}
+
+			if match := registrationDatePattern.FindStringSubmatch(page); match != nil {
+				tld.DelegationDate = match[1]
+				tld.IntroducedYear, _ = strconv.Atoi(match[2])
+			}
+		}(&tlds[index])
+	}
+
+	waitGroup.Wait()
+
+	return tlds, nil
+}
+
+func main() {
+	output := flag.String("o", "tlds.json", "file to write")
+	source := flag.String("source", "iana", "where to read the TLDs from, iana or zlint")
+	zlintVersion := flag.String("zlint-version", "v3.6.8", "release of zlint to read the TLDs from")
+	flag.Parse()
+
+	var tlds []TLD
+	var err error
+
+	switch *source {
+	case "iana":
+		tlds, err = ianaTLDs()
+	case "zlint":
+		tlds, err = zlintTLDs(*zlintVersion)
+	default:
+		err = fmt.Errorf("unknown source %q", *source)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := json.MarshalIndent(tlds, "", "  ")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, append(data, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,402 @@
+// Package publicsuffix looks up the public suffix of domain names. It is the
+// library behind the publicsuffix.stefan-dev.de web service and can be
+// imported by other Go programs that do not want to go through HTTP.
+package publicsuffix
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	ManagedByIcann         = "ICANN"
+	ManagedByPrivateEntity = "PRIVATE_ENTITY"
+	ManagedByNone          = "NONE"
+)
+
+// The contexts a public suffix applies in, see Result.Context.
+const (
+	ContextHTTP = "http"
+	ContextDNS  = "dns"
+	ContextBoth = "both"
+)
+
+// contexts maps the isManagedBy value of a public suffix to its context.
+var contexts = map[string]string{
+	ManagedByIcann:         ContextBoth,
+	ManagedByPrivateEntity: ContextHTTP,
+	ManagedByNone:          ContextDNS,
+}
+
+// Options tunes how domains are validated by Lookup and BatchLookup. The zero
+// value is ready to use.
+type Options struct {
+	// MaxLabelCount is the maximum number of labels accepted in a domain. It
+	// defaults to DefaultMaxLabelCount when zero.
+	MaxLabelCount int
+
+	// AllowUnderscore accepts underscores in labels, e.g. `_dmarc.example.com`
+	// or `my_host.example.com`, which are no valid host names but common in
+	// DNS. Labels must not end with an underscore, though.
+	AllowUnderscore bool
+
+	// ResolveDNS additionally resolves the domain, waiting at most DNSTimeout
+	// (DefaultDNSTimeout when zero) for an answer. Cached answers are still
+	// returned for DNSStaleGrace after they expired, while they are refreshed
+	// in the background.
+	ResolveDNS    bool
+	DNSTimeout    time.Duration
+	DNSStaleGrace time.Duration
+
+	// CheckDNSSEC additionally asks the resolver whether the zone of the
+	// domain is signed, also waiting at most DNSTimeout for an answer.
+	CheckDNSSEC bool
+
+	// AllCandidates additionally returns the public suffix according to the
+	// ICANN section of the list when the private section overrides it.
+	AllCandidates bool
+
+	// Explain additionally describes in plain English why the domain has its
+	// public suffix.
+	Explain bool
+
+	// Strict skips NormalizeDomain and the conversion of IDNs to Punycode, so
+	// that the public suffix of the domain as given is looked up. Uppercase
+	// letters, URLs or IDNs then yield unexpected results, which is mostly
+	// useful for debugging.
+	Strict bool
+
+	// Diagnostics additionally describes how the lookup came to its result.
+	Diagnostics bool
+
+	// Registry is asked for the public suffixes of domains. It defaults to
+	// DefaultRegistry when nil.
+	Registry Registry
+}
+
+type Candidate struct {
+	PublicSuffix string `json:"publicSuffix"`
+	IsManagedBy  string `json:"isManagedBy"`
+}
+
+type Result struct {
+	Domain       string   `json:"domain"`
+	PublicSuffix string   `json:"publicSuffix"`
+	IsManagedBy  string   `json:"isManagedBy"`
+	LabelCount   int      `json:"labelCount"`
+	Labels       []string `json:"labels"`
+
+	// Normalized is false when the domain was looked up as given, see
+	// Options.Strict.
+	Normalized bool `json:"normalized"`
+
+	// IsManagedByLabel describes IsManagedBy in the locale requested from the
+	// web service with `?locale=`. Lookup leaves it empty.
+	IsManagedByLabel string `json:"isManagedByLabel,omitempty"`
+
+	// NetSuffix is the public suffix according to the ICANN section of the
+	// list, e.g. `com` for `example.blogspot.com`, i.e. the one DNS
+	// delegations happen at. Context tells where PublicSuffix applies: the
+	// private suffixes only scope cookies and origins in browsers (`http`),
+	// TLDs missing from the list are only zone cuts in DNS (`dns`), and the
+	// ICANN suffixes are both.
+	NetSuffix string `json:"netSuffix"`
+	Context   string `json:"context"`
+
+	// RegistrarSuffix is an alias of NetSuffix under the name clients asking
+	// for the registrar-level suffix look for.
+	RegistrarSuffix string `json:"registrarSuffix"`
+
+	// PublicSuffixUnicode is PublicSuffix with its Punycode labels decoded,
+	// e.g. `рф` for `xn--p1ai`, for displaying it to end users.
+	PublicSuffixUnicode string `json:"publicSuffixUnicode"`
+
+	// IsPublicSuffix is true when domain is a public suffix itself, e.g.
+	// `co.uk`, and therefore cannot be registered.
+	IsPublicSuffix bool `json:"isPublicSuffix"`
+
+	// IsAtRegistrableLevel is true when domain is the registrable domain
+	// itself, e.g. `example.co.uk`, rather than one of its subdomains or a
+	// public suffix.
+	IsAtRegistrableLevel bool `json:"isAtRegistrableLevel"`
+
+	// ETLDPlusTwo is the registrable domain along with the label in front of
+	// it, e.g. `b.example.co.uk` for `a.b.example.co.uk`, to tell apart the
+	// tenants of multi-tenant services. It is the registrable domain itself
+	// when there is no such label, and empty for public suffixes.
+	ETLDPlusTwo string `json:"etldPlusTwo,omitempty"`
+
+	// TLDIntroducedYear and TLDDelegationDate, e.g. `2014` and `2014-02-06`,
+	// tell when the TLD of the domain was added to the root zone, as young
+	// TLDs are over-represented in phishing campaigns. They are zero and empty
+	// for TLDs missing from the embedded IANA Root Zone Database.
+	TLDIntroducedYear int    `json:"tldIntroducedYear"`
+	TLDDelegationDate string `json:"tldDelegationDate"`
+
+	// Entropy is the Shannon entropy of the label in front of the public
+	// suffix. Randomly generated domains tend to score high, but it is an
+	// informational value and no verdict on the domain.
+	Entropy float64 `json:"entropy"`
+
+	// IDNAValid is false when the domain is not valid according to IDNA2008,
+	// e.g. because of a disallowed rune. IDNAError then describes why.
+	IDNAValid bool   `json:"idnaValid"`
+	IDNAError string `json:"idnaError,omitempty"`
+
+	// Only set when Options.AllCandidates is enabled. The first candidate is
+	// always the one of PublicSuffix and IsManagedBy.
+	AllCandidates []Candidate `json:"allCandidates,omitempty"`
+
+	// Only set when Options.Explain is enabled.
+	Explanation string `json:"explanation,omitempty"`
+
+	// Only set when Options.Diagnostics is enabled.
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+
+	// Only set by the web service when a reputation API is configured. When
+	// it cannot be reached, ReputationScore is -1 and ReputationUnavailable
+	// is true.
+	ReputationScore       *float64 `json:"reputationScore,omitempty"`
+	ReputationSource      string   `json:"reputationSource,omitempty"`
+	ReputationUnavailable bool     `json:"reputationUnavailable,omitempty"`
+
+	// Only set when Options.ResolveDNS is enabled.
+	DNSResolvable *bool    `json:"dnsResolvable,omitempty"`
+	DNSAddresses  []string `json:"dnsAddresses,omitempty"`
+
+	// DNSStale is true when DNSAddresses is an expired answer from the cache,
+	// see Options.DNSStaleGrace. It is not encoded.
+	DNSStale bool `json:"-"`
+
+	// Only set when Options.CheckDNSSEC is enabled. Failures and timeouts
+	// count as not enabled.
+	DNSSECEnabled *bool `json:"dnssecEnabled,omitempty"`
+}
+
+// Lookup normalizes and validates domain and returns its public suffix.
+func Lookup(domain string, opts Options) (Result, error) {
+	return LookupContext(context.Background(), domain, opts)
+}
+
+// LookupContext is like Lookup, but gives up with ctx.Err() once ctx is done,
+// e.g. because the client that requested the lookup went away.
+func LookupContext(ctx context.Context, domain string, opts Options) (Result, error) {
+	prepared, err := prepareDomain(domain, opts)
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	domain, idnaErr := prepared.domain, prepared.idnaErr
+
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	registry := opts.registry()
+	publicSuffix, isManagedBy := lookupPublicSuffix(registry, domain)
+
+	labels := strings.Split(domain, ".")
+
+	// Guards against giant responses should validation ever be relaxed.
+	if len(labels) > DefaultMaxLabelCount {
+		labels = labels[:DefaultMaxLabelCount]
+	}
+
+	result := Result{
+		Domain:       domain,
+		PublicSuffix: publicSuffix,
+		IsManagedBy:  isManagedBy,
+		LabelCount:   strings.Count(domain, ".") + 1,
+		Labels:       labels,
+		Normalized:   !opts.Strict,
+		Context:      contexts[isManagedBy],
+
+		PublicSuffixUnicode: publicSuffixUnicode(publicSuffix),
+
+		IsPublicSuffix:       publicSuffix == domain,
+		IsAtRegistrableLevel: isAtRegistrableLevel(registry, domain),
+		ETLDPlusTwo:          etldPlusTwo(domain, publicSuffix),
+		Entropy:              shannonEntropy(registrableLabel(domain, publicSuffix)),
+		IDNAValid:            idnaErr == nil,
+	}
+
+	if idnaErr != nil {
+		result.IDNAError = strings.TrimPrefix(idnaErr.Error(), "idna: ")
+	}
+
+	if tldInfo, exists := tlds[labels[len(labels)-1]]; exists {
+		if tldInfo.IntroducedYear != nil {
+			result.TLDIntroducedYear = *tldInfo.IntroducedYear
+		}
+
+		if tldInfo.DelegationDate != nil {
+			result.TLDDelegationDate = *tldInfo.DelegationDate
+		}
+	}
+
+	netSuffix, netSuffixIsManagedBy := lookupNetSuffix(registry, publicSuffix, isManagedBy)
+	result.NetSuffix = netSuffix
+	result.RegistrarSuffix = netSuffix
+
+	if opts.AllCandidates {
+		result.AllCandidates = []Candidate{{PublicSuffix: publicSuffix, IsManagedBy: isManagedBy}}
+
+		if isManagedBy == ManagedByPrivateEntity && netSuffixIsManagedBy == ManagedByIcann {
+			result.AllCandidates = append(result.AllCandidates, Candidate{PublicSuffix: netSuffix, IsManagedBy: netSuffixIsManagedBy})
+		}
+	}
+
+	if opts.Explain {
+		result.Explanation = explain(domain, publicSuffix, isManagedBy)
+	}
+
+	if opts.Diagnostics {
+		result.Diagnostics = diagnose(registry, domain, publicSuffix, isManagedBy, opts)
+	}
+
+	if opts.ResolveDNS {
+		result.DNSAddresses, result.DNSStale = resolveDNS(ctx, domain, opts.DNSTimeout, opts.DNSStaleGrace)
+
+		// Resolving, unless answered from the DNS cache, is what takes long
+		// enough for the client to give up.
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		dnsResolvable := len(result.DNSAddresses) > 0
+		result.DNSResolvable = &dnsResolvable
+	}
+
+	if opts.CheckDNSSEC {
+		dnssecEnabled := checkDNSSEC(ctx, registry, domain, opts.DNSTimeout)
+
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		result.DNSSECEnabled = &dnssecEnabled
+	}
+
+	return result, nil
+}
+
+// Validate normalizes and validates domain like Lookup, but without looking up
+// its public suffix, and returns the domain as it would be looked up.
+func Validate(domain string, opts Options) (string, error) {
+	prepared, err := prepareDomain(domain, opts)
+
+	return prepared.domain, err
+}
+
+// preparedDomain is a domain normalized and validated by prepareDomain.
+type preparedDomain struct {
+	domain string
+
+	// idnaErr is only informational, as ToASCII returns the ASCII form on a
+	// best-effort basis even if the domain is invalid.
+	//
+	// See: https://pkg.go.dev/golang.org/x/net/idna#Profile.ToASCII
+	idnaErr error
+}
+
+// prepareDomain normalizes domain into the ASCII form the public suffix list
+// is matched in, unless opts.Strict is set, and validates it.
+func prepareDomain(domain string, opts Options) (preparedDomain, error) {
+	if !opts.Strict {
+		domain = NormalizeDomain(domain)
+	}
+
+	asciiDomain, idnaErr := idna.Lookup.ToASCII(domain)
+
+	if asciiDomain != "" && !opts.Strict {
+		domain = asciiDomain
+	}
+
+	if err := ValidateDomain(domain, opts); err != nil {
+		return preparedDomain{idnaErr: idnaErr}, err
+	}
+
+	return preparedDomain{domain: domain, idnaErr: idnaErr}, nil
+}
+
+// publicSuffixUnicode decodes the Punycode labels of publicSuffix and returns
+// it unchanged if that fails.
+//
+// See: https://pkg.go.dev/golang.org/x/net/idna#ToUnicode
+func publicSuffixUnicode(publicSuffix string) string {
+	unicodePublicSuffix, err := idna.ToUnicode(publicSuffix)
+
+	if err != nil {
+		return publicSuffix
+	}
+
+	return unicodePublicSuffix
+}
+
+// isAtRegistrableLevel reports whether domain equals its eTLD+1. Public
+// suffixes such as bare TLDs have none.
+func isAtRegistrableLevel(registry Registry, domain string) bool {
+	registrableDomain, err := effectiveTLDPlusOne(registry, domain)
+
+	return err == nil && registrableDomain == domain
+}
+
+// etldPlusTwo returns the last two labels in front of publicSuffix along with
+// publicSuffix, or an empty string when there are none.
+func etldPlusTwo(domain string, publicSuffix string) string {
+	if domain == publicSuffix || !strings.HasSuffix(domain, "."+publicSuffix) {
+		return ""
+	}
+
+	labels := strings.Split(strings.TrimSuffix(domain, "."+publicSuffix), ".")
+
+	return strings.Join(labels[max(len(labels)-2, 0):], ".") + "." + publicSuffix
+}
+
+// lookupNetSuffix returns publicSuffix unless it is a private suffix such as
+// `blogspot.com`. Such a suffix is nested in an ICANN one, which is found by
+// looking up its parent domain.
+func lookupNetSuffix(registry Registry, publicSuffix string, isManagedBy string) (string, string) {
+	for isManagedBy == ManagedByPrivateEntity {
+		_, parent, _ := strings.Cut(publicSuffix, ".")
+		publicSuffix, isManagedBy = lookupPublicSuffix(registry, parent)
+	}
+
+	return publicSuffix, isManagedBy
+}
+
+func lookupPublicSuffix(registry Registry, domain string) (string, string) {
+	publicSuffix, isIcannManaged := registry.PublicSuffix(domain)
+
+	// See: https://pkg.go.dev/golang.org/x/net/publicsuffix#example-PublicSuffix-Manager
+	if isIcannManaged {
+		return publicSuffix, ManagedByIcann
+	} else if strings.IndexByte(publicSuffix, '.') >= 0 {
+		return publicSuffix, ManagedByPrivateEntity
+	}
+
+	return publicSuffix, ManagedByNone
+}
+
+// BatchLookup calls Lookup for every domain and returns the results in the
+// same order. It stops at the first domain that fails validation.
+func BatchLookup(domains []string, opts Options) ([]Result, error) {
+	results := make([]Result, len(domains))
+
+	for index, domain := range domains {
+		result, err := Lookup(domain, opts)
+
+		if err != nil {
+			return nil, fmt.Errorf("domain at index %d: %w", index, err)
+		}
+
+		results[index] = result
+	}
+
+	return results, nil
+}
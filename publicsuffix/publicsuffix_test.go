@@ -0,0 +1,97 @@
+package publicsuffix
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPublicSuffixUnicode(t *testing.T) {
+	tests := []struct {
+		name         string
+		domain       string
+		publicSuffix string
+		unicode      string
+	}{
+		{"Cyrillic", "пример.рф", "xn--p1ai", "рф"},
+		{"CJK", "例子.中国", "xn--fiqs8s", "中国"},
+		{"Arabic", "مثال.مصر", "xn--wgbh1c", "مصر"},
+		{"ASCII", "example.co.uk", "co.uk", "co.uk"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Lookup(test.domain, Options{})
+
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", test.domain, err)
+			}
+
+			if result.PublicSuffix != test.publicSuffix || result.PublicSuffixUnicode != test.unicode {
+				t.Errorf("Lookup(%q) = %s (%s), want %s (%s)", test.domain, result.PublicSuffix, result.PublicSuffixUnicode, test.publicSuffix, test.unicode)
+			}
+		})
+	}
+}
+
+func TestLookupContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := LookupContext(ctx, "example.co.uk", Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("LookupContext() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestIsAtRegistrableLevel(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"example.com", true},
+		{"www.example.com", false},
+		{"com", false},
+		{"example.co.uk", true},
+		{"co.uk", false},
+		{"example.github.io", true},
+		{"www.example.github.io", false},
+		{"github.io", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.domain, func(t *testing.T) {
+			result, err := Lookup(test.domain, Options{})
+
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", test.domain, err)
+			}
+
+			if result.IsAtRegistrableLevel != test.want {
+				t.Errorf("Lookup(%q).IsAtRegistrableLevel = %t, want %t", test.domain, result.IsAtRegistrableLevel, test.want)
+			}
+		})
+	}
+}
+
+func TestEtldPlusTwo(t *testing.T) {
+	tests := []struct {
+		name         string
+		domain       string
+		publicSuffix string
+		want         string
+	}{
+		{"single label", "com", "com", ""},
+		{"single label in front", "example.com", "com", "example.com"},
+		{"double label", "www.example.com", "com", "www.example.com"},
+		{"deeply nested", "a.b.c.www.example.co.uk", "co.uk", "www.example.co.uk"},
+		{"other suffix", "example.com", "org", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if etldPlusTwo := etldPlusTwo(test.domain, test.publicSuffix); etldPlusTwo != test.want {
+				t.Errorf("etldPlusTwo(%q, %q) = %q, want %q", test.domain, test.publicSuffix, etldPlusTwo, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package publicsuffix
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Registry tells the public suffix of a domain and whether it is managed by
+// ICANN, like golang.org/x/net/publicsuffix.PublicSuffix. Lookups use
+// DefaultRegistry unless Options.Registry is set, e.g. to a fixed set of
+// suffixes in tests that should not depend on the current list.
+type Registry interface {
+	PublicSuffix(domain string) (publicSuffix string, icann bool)
+}
+
+// DefaultRegistry is the public suffix list compiled into
+// golang.org/x/net/publicsuffix.
+var DefaultRegistry Registry = netRegistry{}
+
+type netRegistry struct{}
+
+func (netRegistry) PublicSuffix(domain string) (string, bool) {
+	return publicsuffix.PublicSuffix(domain)
+}
+
+func (opts Options) registry() Registry {
+	if opts.Registry == nil {
+		return DefaultRegistry
+	}
+
+	return opts.Registry
+}
+
+// effectiveTLDPlusOne is golang.org/x/net/publicsuffix.EffectiveTLDPlusOne for
+// any registry, for domains already validated by prepareDomain.
+//
+// See: https://pkg.go.dev/golang.org/x/net/publicsuffix#EffectiveTLDPlusOne
+func effectiveTLDPlusOne(registry Registry, domain string) (string, error) {
+	publicSuffix, _ := registry.PublicSuffix(domain)
+
+	if len(domain) <= len(publicSuffix) {
+		return "", fmt.Errorf("cannot derive eTLD+1 for domain %q", domain)
+	}
+
+	index := len(domain) - len(publicSuffix) - 1
+
+	if domain[index] != '.' {
+		return "", fmt.Errorf("invalid public suffix %q for domain %q", publicSuffix, domain)
+	}
+
+	return domain[1+strings.LastIndex(domain[:index], "."):], nil
+}
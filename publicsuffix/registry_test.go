@@ -0,0 +1,71 @@
+package publicsuffix
+
+import (
+	"strings"
+	"testing"
+)
+
+// mockRegistry knows only its suffixes, mapped to whether they are managed by
+// ICANN, so that tests do not depend on the current list.
+type mockRegistry map[string]bool
+
+func (registry mockRegistry) PublicSuffix(domain string) (string, bool) {
+	for suffix := domain; ; {
+		if icann, exists := registry[suffix]; exists {
+			return suffix, icann
+		}
+
+		_, parent, found := strings.Cut(suffix, ".")
+
+		if !found {
+			return suffix, false
+		}
+
+		suffix = parent
+	}
+}
+
+func TestLookupWithRegistry(t *testing.T) {
+	registry := mockRegistry{"test": true, "example.test": false}
+
+	tests := []struct {
+		domain       string
+		publicSuffix string
+		isManagedBy  string
+		netSuffix    string
+	}{
+		{"www.foo.example.test", "example.test", ManagedByPrivateEntity, "test"},
+		{"foo.test", "test", ManagedByIcann, "test"},
+		{"example.com", "com", ManagedByNone, "com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.domain, func(t *testing.T) {
+			result, err := Lookup(test.domain, Options{Registry: registry})
+
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", test.domain, err)
+			}
+
+			if result.PublicSuffix != test.publicSuffix || result.IsManagedBy != test.isManagedBy || result.NetSuffix != test.netSuffix {
+				t.Errorf("Lookup(%q) = %s %s %s, want %s %s %s", test.domain, result.PublicSuffix, result.IsManagedBy, result.NetSuffix, test.publicSuffix, test.isManagedBy, test.netSuffix)
+			}
+
+			if result.RegistrarSuffix != result.NetSuffix {
+				t.Errorf("Lookup(%q).RegistrarSuffix = %s, want the netSuffix %s", test.domain, result.RegistrarSuffix, result.NetSuffix)
+			}
+		})
+	}
+}
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	registry := mockRegistry{"test": true, "example.test": false}
+
+	if registrableDomain, err := effectiveTLDPlusOne(registry, "www.foo.example.test"); err != nil || registrableDomain != "foo.example.test" {
+		t.Errorf("effectiveTLDPlusOne() = %q, %v, want foo.example.test", registrableDomain, err)
+	}
+
+	if _, err := effectiveTLDPlusOne(registry, "example.test"); err == nil {
+		t.Error("effectiveTLDPlusOne() of a public suffix succeeded")
+	}
+}
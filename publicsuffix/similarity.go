@@ -0,0 +1,181 @@
+package publicsuffix
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables.json maps the characters allowed in internationalized domain
+// names to the lowercase prototypes they are mistaken for, e.g. the Cyrillic
+// `а` to `a` or `m` to `rn`. It is the subset of the Unicode confusables that
+// can occur in a valid domain.
+//
+// See: https://www.unicode.org/reports/tr39/#Confusable_Detection
+//
+//go:generate go run ./internal/genconfusables -o confusables.json
+//go:embed confusables.json
+var embeddedConfusables []byte
+
+var confusables = func() map[rune]string {
+	entries := map[string]string{}
+
+	if err := json.Unmarshal(embeddedConfusables, &entries); err != nil {
+		panic(err)
+	}
+
+	confusables := make(map[rune]string, len(entries))
+
+	for character, prototype := range entries {
+		confusables[[]rune(character)[0]] = prototype
+	}
+
+	return confusables
+}()
+
+const SimilarityAlgorithm = "jaro-winkler"
+
+type Similarity struct {
+	// Score is the Jaro-Winkler similarity of the labels in front of the
+	// public suffixes of both domains, e.g. about 0.94 for `example` and
+	// `examp1e`, from 0 for nothing in common to 1 for identical labels.
+	Score     float64 `json:"score"`
+	Algorithm string  `json:"algorithm"`
+
+	// RegistrableSame is true when both domains have the same registrable
+	// domain, e.g. `www.example.com` and `mail.example.com`.
+	RegistrableSame bool `json:"registrableSame"`
+
+	// Confusable is true when the labels differ, but have the same skeleton,
+	// e.g. `exаmple` with a Cyrillic `а` or `examp1e`.
+	Confusable bool `json:"confusable"`
+}
+
+// CompareDomains normalizes and validates both domains like Lookup and returns
+// how similar they look, e.g. to detect lookalike domains used for phishing.
+// The public suffixes are left out, as `example.com` and `example.net` are
+// lookalikes no matter how different their suffixes are.
+func CompareDomains(domain1 string, domain2 string, opts Options) (Similarity, error) {
+	prepared1, err := prepareDomain(domain1, opts)
+
+	if err != nil {
+		return Similarity{}, err
+	}
+
+	prepared2, err := prepareDomain(domain2, opts)
+
+	if err != nil {
+		return Similarity{}, err
+	}
+
+	domain1, domain2 = prepared1.domain, prepared2.domain
+
+	registry := opts.registry()
+
+	publicSuffix1, _ := registry.PublicSuffix(domain1)
+	publicSuffix2, _ := registry.PublicSuffix(domain2)
+
+	// Punycode hides the characters that make labels confusable.
+	label1 := publicSuffixUnicode(registrableLabel(domain1, publicSuffix1))
+	label2 := publicSuffixUnicode(registrableLabel(domain2, publicSuffix2))
+
+	registrableDomain1, err1 := effectiveTLDPlusOne(registry, domain1)
+	registrableDomain2, err2 := effectiveTLDPlusOne(registry, domain2)
+
+	return Similarity{
+		Score:           jaroWinkler(label1, label2),
+		Algorithm:       SimilarityAlgorithm,
+		RegistrableSame: err1 == nil && err2 == nil && registrableDomain1 == registrableDomain2,
+		Confusable:      label1 != label2 && skeleton(label1) == skeleton(label2),
+	}, nil
+}
+
+// skeleton replaces the confusable characters of label with their
+// prototypes, after decomposing it, so that accents are compared separately.
+//
+// See: https://www.unicode.org/reports/tr39/#def-skeleton
+func skeleton(label string) string {
+	builder := strings.Builder{}
+
+	for _, character := range norm.NFD.String(label) {
+		if prototype, isConfusable := confusables[character]; isConfusable {
+			builder.WriteString(prototype)
+		} else {
+			builder.WriteRune(character)
+		}
+	}
+
+	return norm.NFD.String(builder.String())
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of value1 and value2, which
+// favors values with a common prefix of up to four characters.
+//
+// See: https://en.wikipedia.org/wiki/Jaro%E2%80%93Winkler_distance
+func jaroWinkler(value1 string, value2 string) float64 {
+	runes1 := []rune(value1)
+	runes2 := []rune(value2)
+
+	if len(runes1) == 0 && len(runes2) == 0 {
+		return 1
+	}
+
+	if len(runes1) == 0 || len(runes2) == 0 {
+		return 0
+	}
+
+	// Characters only match within this distance of each other.
+	matchDistance := max(max(len(runes1), len(runes2))/2-1, 0)
+
+	matched1 := make([]bool, len(runes1))
+	matched2 := make([]bool, len(runes2))
+	matches := 0
+
+	for index1, character := range runes1 {
+		for index2 := max(index1-matchDistance, 0); index2 < min(index1+matchDistance+1, len(runes2)); index2++ {
+			if !matched2[index2] && runes2[index2] == character {
+				matched1[index1] = true
+				matched2[index2] = true
+				matches++
+
+				break
+			}
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	// Matching characters in a different order count as half a transposition.
+	transpositions := 0
+	index2 := 0
+
+	for index1, character := range runes1 {
+		if !matched1[index1] {
+			continue
+		}
+
+		for !matched2[index2] {
+			index2++
+		}
+
+		if runes2[index2] != character {
+			transpositions++
+		}
+
+		index2++
+	}
+
+	jaro := (float64(matches)/float64(len(runes1)) + float64(matches)/float64(len(runes2)) + float64(matches-transpositions/2)/float64(matches)) / 3
+
+	prefixLength := 0
+
+	for prefixLength < min(4, len(runes1), len(runes2)) && runes1[prefixLength] == runes2[prefixLength] {
+		prefixLength++
+	}
+
+	return jaro + float64(prefixLength)*0.1*(1-jaro)
+}
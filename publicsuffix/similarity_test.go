@@ -0,0 +1,61 @@
+package publicsuffix
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareDomains(t *testing.T) {
+	tests := []struct {
+		domain1         string
+		domain2         string
+		score           float64
+		registrableSame bool
+		confusable      bool
+	}{
+		// 6 of 7 characters match, the first 4 being a common prefix.
+		{"example.com", "examp1e.com", 19.0/21 + 0.4*(1-19.0/21), false, true},
+		// The Cyrillic `а` ends the common prefix after 2 characters.
+		{"example.com", "exаmple.com", 19.0/21 + 0.2*(1-19.0/21), false, true},
+		{"example.com", "example.net", 1, false, false},
+		{"www.example.com", "mail.example.com", 1, true, false},
+		{"example.com", "bdfgh.com", 0, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.domain2, func(t *testing.T) {
+			similarity, err := CompareDomains(test.domain1, test.domain2, Options{})
+
+			if err != nil {
+				t.Fatalf("CompareDomains() error = %v", err)
+			}
+
+			if math.Abs(similarity.Score-test.score) > 1e-9 {
+				t.Errorf("Score = %f, want %f", similarity.Score, test.score)
+			}
+
+			if similarity.RegistrableSame != test.registrableSame || similarity.Confusable != test.confusable {
+				t.Errorf("RegistrableSame, Confusable = %t, %t, want %t, %t", similarity.RegistrableSame, similarity.Confusable, test.registrableSame, test.confusable)
+			}
+		})
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		label    string
+		skeleton string
+	}{
+		{"example", "exarnple"},
+		{"examp1e", "exarnple"},
+		{"exаmple", "exarnple"},
+		{"g00gle", "google"},
+		{"café", "café"},
+	}
+
+	for _, test := range tests {
+		if skeleton := skeleton(test.label); skeleton != test.skeleton {
+			t.Errorf("skeleton(%q) = %q, want %q", test.label, skeleton, test.skeleton)
+		}
+	}
+}
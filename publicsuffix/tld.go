@@ -0,0 +1,85 @@
+package publicsuffix
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// tlds.json is JSON rather than CSV, like confusables.json, so that it decodes
+// into named fields with encoding/json and the dates unknown for a TLD, e.g.
+// of the country-code ones, are simply left out.
+//
+//go:generate go run ./internal/gentlds -source zlint -o tlds.json
+
+//go:embed tlds.json
+var embeddedTLDs []byte
+
+var ErrNotATLD = errors.New("not a top-level domain")
+
+// TLDInfo is the metadata known about a top-level domain. Type,
+// IntroducedYear and DelegationDate are nil for TLDs missing from tlds.json,
+// the latter two also for the country-code TLDs, whose delegation dates the
+// TLD map of zlint that it is generated from does not list.
+type TLDInfo struct {
+	TLD            string  `json:"tld"`
+	Type           *string `json:"type"`
+	IcannManaged   bool    `json:"icannManaged"`
+	IntroducedYear *int    `json:"introducedYear"`
+	DelegationDate *string `json:"delegationDate"`
+}
+
+var tlds = func() map[string]TLDInfo {
+	entries := []struct {
+		TLD            string `json:"tld"`
+		Type           string `json:"type"`
+		IntroducedYear int    `json:"introducedYear"`
+		DelegationDate string `json:"delegationDate"`
+	}{}
+
+	if err := json.Unmarshal(embeddedTLDs, &entries); err != nil {
+		panic(err)
+	}
+
+	tlds := make(map[string]TLDInfo, len(entries))
+
+	for index := range entries {
+		entry := &entries[index]
+		tldInfo := TLDInfo{TLD: entry.TLD, Type: &entry.Type}
+
+		if entry.IntroducedYear != 0 {
+			tldInfo.IntroducedYear = &entry.IntroducedYear
+		}
+
+		if entry.DelegationDate != "" {
+			tldInfo.DelegationDate = &entry.DelegationDate
+		}
+
+		tlds[entry.TLD] = tldInfo
+	}
+
+	return tlds
+}()
+
+// LookupTLD returns the metadata of tld, which may be given with or without
+// its leading dot.
+func LookupTLD(tld string) (TLDInfo, error) {
+	tld = strings.TrimPrefix(NormalizeDomain(tld), ".")
+
+	if tld == "" || strings.Contains(tld, ".") {
+		return TLDInfo{}, ErrNotATLD
+	}
+
+	tldInfo, exists := tlds[tld]
+
+	if !exists {
+		tldInfo = TLDInfo{TLD: tld}
+	}
+
+	_, tldInfo.IcannManaged = publicsuffix.PublicSuffix(tld)
+
+	return tldInfo, nil
+}
@@ -0,0 +1,88 @@
+package publicsuffix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookupTLD(t *testing.T) {
+	tests := []struct {
+		tld            string
+		tldType        string
+		introducedYear int
+		delegationDate string
+	}{
+		{"com", "generic", 1985, "1985-01-01"},
+		{".xyz", "generic", 2014, "2014-02-19"},
+		{"app", "generic", 2015, "2015-07-02"},
+		{"dev", "generic", 2014, "2014-12-18"},
+		{"biz", "generic-restricted", 2001, "2001-09-25"},
+		{"arpa", "infrastructure", 1985, "1985-01-01"},
+		{"de", "country-code", 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.tld, func(t *testing.T) {
+			tldInfo, err := LookupTLD(test.tld)
+
+			if err != nil {
+				t.Fatalf("LookupTLD(%q) error = %v", test.tld, err)
+			}
+
+			if tldInfo.Type == nil || *tldInfo.Type != test.tldType {
+				t.Errorf("Type = %v, want %s", tldInfo.Type, test.tldType)
+			}
+
+			if test.introducedYear == 0 {
+				if tldInfo.IntroducedYear != nil || tldInfo.DelegationDate != nil {
+					t.Errorf("IntroducedYear = %v, DelegationDate = %v, want nil", tldInfo.IntroducedYear, tldInfo.DelegationDate)
+				}
+
+				return
+			}
+
+			if tldInfo.IntroducedYear == nil || *tldInfo.IntroducedYear != test.introducedYear {
+				t.Errorf("IntroducedYear = %v, want %d", tldInfo.IntroducedYear, test.introducedYear)
+			}
+
+			if tldInfo.DelegationDate == nil || *tldInfo.DelegationDate != test.delegationDate {
+				t.Errorf("DelegationDate = %v, want %s", tldInfo.DelegationDate, test.delegationDate)
+			}
+		})
+	}
+}
+
+func TestLookupTLDRejectsDomains(t *testing.T) {
+	for _, tld := range []string{"", "example.com"} {
+		if _, err := LookupTLD(tld); !errors.Is(err, ErrNotATLD) {
+			t.Errorf("LookupTLD(%q) = %v, want %v", tld, err, ErrNotATLD)
+		}
+	}
+}
+
+func TestLookupTLDAge(t *testing.T) {
+	tests := []struct {
+		domain            string
+		tldIntroducedYear int
+		tldDelegationDate string
+	}{
+		{"example.app", 2015, "2015-07-02"},
+		{"www.example.com", 1985, "1985-01-01"},
+		{"example.de", 0, ""},
+		{"example.internal", 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.domain, func(t *testing.T) {
+			result, err := Lookup(test.domain, Options{})
+
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", test.domain, err)
+			}
+
+			if result.TLDIntroducedYear != test.tldIntroducedYear || result.TLDDelegationDate != test.tldDelegationDate {
+				t.Errorf("Lookup(%q) = %d %q, want %d %q", test.domain, result.TLDIntroducedYear, result.TLDDelegationDate, test.tldIntroducedYear, test.tldDelegationDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package publicsuffix
+
+import "strings"
+
+// MaxTreeDepth is the number of labels below which Decompose stops.
+const MaxTreeDepth = 10
+
+// TreeNode is a label of a domain along with its ancestors, e.g. `co` of
+// `www.example.co.uk` standing for `co.uk`.
+type TreeNode struct {
+	Label          string      `json:"label"`
+	Domain         string      `json:"domain"`
+	IsTLD          bool        `json:"isTLD"`
+	IsPublicSuffix bool        `json:"isPublicSuffix"`
+	IsRegistrable  bool        `json:"isRegistrable"`
+	Children       []*TreeNode `json:"children,omitempty"`
+}
+
+// Decompose normalizes and validates domain like Lookup and returns it as a
+// tree from its TLD down to its first label, e.g. for visualizing it. Every
+// node has at most one child, labels beyond MaxTreeDepth are left out.
+func Decompose(domain string, opts Options) (*TreeNode, error) {
+	prepared, err := prepareDomain(domain, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	domain = prepared.domain
+
+	registry := opts.registry()
+	labels := strings.Split(domain, ".")
+
+	var root, parent *TreeNode
+
+	for depth := 0; depth < len(labels) && depth < MaxTreeDepth; depth++ {
+		nodeDomain := strings.Join(labels[len(labels)-1-depth:], ".")
+		publicSuffix, _ := lookupPublicSuffix(registry, nodeDomain)
+
+		treeNode := &TreeNode{
+			Label:          labels[len(labels)-1-depth],
+			Domain:         nodeDomain,
+			IsTLD:          depth == 0,
+			IsPublicSuffix: publicSuffix == nodeDomain,
+			IsRegistrable:  isAtRegistrableLevel(registry, nodeDomain),
+		}
+
+		if parent == nil {
+			root = treeNode
+		} else {
+			parent.Children = []*TreeNode{treeNode}
+		}
+
+		parent = treeNode
+	}
+
+	return root, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	// rateLimiterEvictionInterval is how often the buckets are swept for
+	// full ones, which are the same as no bucket at all.
+	rateLimiterEvictionInterval = time.Minute
+)
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter is a token bucket per key, e.g. per IP address, that is refilled
+// with rps tokens per second up to burst tokens.
+//
+// With a redisRateLimitStore, requests are counted in Redis instead, allowing
+// burst requests within the time the bucket takes to fill up. The token
+// buckets are only used while Redis is unavailable then.
+//
+// See: https://en.wikipedia.org/wiki/Token_bucket
+type RateLimiter struct {
+	rps   float64
+	burst float64
+
+	redisRateLimitStore *RedisRateLimitStore
+	redisKeyPrefix      string
+
+	mutex       sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastEvicted time.Time
+}
+
+func newRateLimiter(rps int, burst int) *RateLimiter {
+	return &RateLimiter{
+		rps:         float64(rps),
+		burst:       float64(burst),
+		buckets:     map[string]*tokenBucket{},
+		lastEvicted: time.Now(),
+	}
+}
+
+// RateLimit is the state of a bucket after a request. Reset is when it will
+// be full again, RetryAfter how long until it holds the next token.
+type RateLimit struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// allow takes a token from the bucket of key and reports whether there was
+// one left, along with what is left now.
+func (rateLimiter *RateLimiter) allow(key string) RateLimit {
+	if rateLimiter.redisRateLimitStore != nil {
+		window := max(rateLimiter.fillTime(rateLimiter.burst), time.Second)
+		rateLimit, err := rateLimiter.redisRateLimitStore.allow(rateLimiter.redisKeyPrefix+key, rateLimiter.burst, window)
+
+		if err == nil {
+			return rateLimit
+		}
+
+		logDebugf("Rate limiting %s in memory: %s", key, err)
+	}
+
+	rateLimiter.mutex.Lock()
+	defer rateLimiter.mutex.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(rateLimiter.lastEvicted) >= rateLimiterEvictionInterval {
+		rateLimiter.evict(now)
+	}
+
+	bucket, exists := rateLimiter.buckets[key]
+
+	if !exists {
+		bucket = &tokenBucket{tokens: rateLimiter.burst, updated: now}
+		rateLimiter.buckets[key] = bucket
+	}
+
+	bucket.tokens = rateLimiter.refill(bucket, now)
+	bucket.updated = now
+
+	allowed := bucket.tokens >= 1
+
+	if allowed {
+		bucket.tokens--
+	}
+
+	return RateLimit{
+		Allowed:    allowed,
+		Limit:      int(rateLimiter.burst),
+		Remaining:  int(bucket.tokens),
+		Reset:      now.Add(rateLimiter.fillTime(rateLimiter.burst - bucket.tokens)),
+		RetryAfter: rateLimiter.fillTime(1 - bucket.tokens),
+	}
+}
+
+// evict deletes the full buckets. It runs once per
+// rateLimiterEvictionInterval rather than whenever there are many buckets, as
+// those would otherwise be scanned on every request while a client keeps
+// sending new keys.
+func (rateLimiter *RateLimiter) evict(now time.Time) {
+	for key, bucket := range rateLimiter.buckets {
+		if rateLimiter.refill(bucket, now) >= rateLimiter.burst {
+			delete(rateLimiter.buckets, key)
+		}
+	}
+
+	rateLimiter.lastEvicted = now
+}
+
+// fillTime returns how long the bucket takes to be refilled with tokens.
+func (rateLimiter *RateLimiter) fillTime(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+
+	return time.Duration(tokens / rateLimiter.rps * float64(time.Second))
+}
+
+func (rateLimiter *RateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.updated).Seconds()*rateLimiter.rps
+
+	if tokens > rateLimiter.burst {
+		return rateLimiter.burst
+	}
+
+	return tokens
+}
+
+// remoteIP returns the IP address of the client, without its port.
+func remoteIP(httpRequest *http.Request) string {
+	ip, _, err := net.SplitHostPort(httpRequest.RemoteAddr)
+
+	if err != nil {
+		return httpRequest.RemoteAddr
+	}
+
+	return ip
+}
+
+// setRateLimitHeaders announces the quota of the client.
+//
+// See: https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api#checking-the-status-of-your-rate-limit
+func setRateLimitHeaders(httpResponseWriter http.ResponseWriter, rateLimit RateLimit) {
+	httpResponseWriter.Header().Set("X-RateLimit-Limit", strconv.Itoa(rateLimit.Limit))
+	httpResponseWriter.Header().Set("X-RateLimit-Remaining", strconv.Itoa(rateLimit.Remaining))
+	httpResponseWriter.Header().Set("X-RateLimit-Reset", strconv.FormatInt(rateLimit.Reset.Unix(), 10))
+}
+
+// setRetryAfterHeader tells clients how long to back off, in whole seconds
+// rounded up, as a client retrying too early would only be rejected again.
+//
+// See: https://www.rfc-editor.org/rfc/rfc9110#field.retry-after
+func setRetryAfterHeader(httpResponseWriter http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	httpResponseWriter.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// rateLimitMiddleware limits requests per client IP address and, for requests
+// carrying an API key, additionally per key. Clients sharing a NAT address
+// thereby still get their own allowance, but can never exceed the one of
+// their address. The headers describe the more restrictive of both limits.
+func (server *Server) rateLimitMiddleware(ipRateLimiter *RateLimiter, apiKeyRateLimiter *RateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		var ipRateLimit, apiKeyRateLimit *RateLimit
+
+		if ipRateLimiter != nil {
+			rateLimit := ipRateLimiter.allow(remoteIP(httpRequest))
+			ipRateLimit = &rateLimit
+		}
+
+		if apiKey := httpRequest.Header.Get(apiKeyHeader); apiKeyRateLimiter != nil && apiKey != "" && (ipRateLimit == nil || ipRateLimit.Allowed) {
+			rateLimit := apiKeyRateLimiter.allow(apiKey)
+			apiKeyRateLimit = &rateLimit
+		}
+
+		headerRateLimit := ipRateLimit
+
+		if apiKeyRateLimit != nil && (headerRateLimit == nil || !apiKeyRateLimit.Allowed || apiKeyRateLimit.Remaining < headerRateLimit.Remaining) {
+			headerRateLimit = apiKeyRateLimit
+		}
+
+		if headerRateLimit != nil {
+			setRateLimitHeaders(httpResponseWriter, *headerRateLimit)
+		}
+
+		if ipRateLimit != nil && !ipRateLimit.Allowed {
+			setRetryAfterHeader(httpResponseWriter, ipRateLimit.RetryAfter)
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusTooManyRequests, ErrorIdRateLimitExceeded, "Rate limit per IP address exceeded")
+			return
+		}
+
+		if apiKeyRateLimit != nil && !apiKeyRateLimit.Allowed {
+			setRetryAfterHeader(httpResponseWriter, apiKeyRateLimit.RetryAfter)
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusTooManyRequests, ErrorIdRateLimitExceeded, "Rate limit per API key exceeded")
+			return
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
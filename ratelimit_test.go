@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	rateLimiter := newRateLimiter(1, 2)
+
+	for index, want := range []bool{true, true, false} {
+		if rateLimit := rateLimiter.allow("client"); rateLimit.Allowed != want {
+			t.Errorf("allow() request %d = %t, want %t", index+1, rateLimit.Allowed, want)
+		}
+	}
+
+	if rateLimit := rateLimiter.allow("other"); !rateLimit.Allowed {
+		t.Error("allow() of another key was rejected")
+	}
+}
+
+func TestRateLimiterEvict(t *testing.T) {
+	rateLimiter := newRateLimiter(1, 2)
+	rateLimiter.allow("idle")
+	rateLimiter.allow("busy")
+	rateLimiter.allow("busy")
+
+	// Once the interval is over, the next request sweeps the buckets.
+	rateLimiter.buckets["idle"].updated = time.Now().Add(-time.Hour)
+	rateLimiter.lastEvicted = time.Now().Add(-rateLimiterEvictionInterval)
+	rateLimiter.allow("busy")
+
+	if _, exists := rateLimiter.buckets["idle"]; exists {
+		t.Error("allow() kept the full bucket")
+	}
+
+	if _, exists := rateLimiter.buckets["busy"]; !exists {
+		t.Error("allow() evicted a bucket that is not full")
+	}
+}
+
+func TestAPIKeyRateLimitIsOffByDefault(t *testing.T) {
+	if config := loadConfig(); config.APIKeyRateLimitRPS != 0 {
+		t.Errorf("APIKeyRateLimitRPS = %d, want 0", config.APIKeyRateLimitRPS)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	server := NewServer(Config{})
+	handler := server.rateLimitMiddleware(newRateLimiter(1, 1), nil, http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {}))
+
+	for index, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		httpResponseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if httpResponseRecorder.Code != want {
+			t.Errorf("request %d status = %d, want %d", index+1, httpResponseRecorder.Code, want)
+		}
+	}
+}
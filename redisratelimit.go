@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sony/gobreaker"
+)
+
+// redisTimeout bounds every command, as requests wait on Redis before being
+// rate limited in memory instead.
+const redisTimeout = 100 * time.Millisecond
+
+// RedisRateLimitStore counts requests in Redis, so that the rate limits are
+// shared by all instances of the service. A circuit breaker stops calling
+// Redis while it keeps failing.
+type RedisRateLimitStore struct {
+	client         *redis.Client
+	circuitBreaker *gobreaker.CircuitBreaker
+}
+
+func newRedisRateLimitStore(redisUrl string) (*RedisRateLimitStore, error) {
+	options, err := redis.ParseURL(redisUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	options.DialTimeout = redisTimeout
+	options.ReadTimeout = redisTimeout
+	options.WriteTimeout = redisTimeout
+
+	// A retry would only delay falling back to the in-memory limiter.
+	options.MaxRetries = -1
+
+	return &RedisRateLimitStore{
+		client: redis.NewClient(options),
+		circuitBreaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name: "redis",
+			OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+				logWarnf("Circuit breaker %s changed from %s to %s", name, from, to)
+			},
+		}),
+	}, nil
+}
+
+// allow counts a request of key in a sliding window of window, which is
+// approximated by weighting the count of the previous fixed window by how
+// much of it still overlaps. Rejected requests are not counted, as with the
+// token buckets.
+//
+// See: https://blog.cloudflare.com/counting-things-a-lot-of-different-things/
+func (redisRateLimitStore *RedisRateLimitStore) allow(key string, limit float64, window time.Duration) (RateLimit, error) {
+	rateLimit, err := redisRateLimitStore.circuitBreaker.Execute(func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+
+		now := time.Now()
+		windowStart := now.Truncate(window)
+		currentKey := fmt.Sprintf("ratelimit:%s:%d", key, windowStart.Unix())
+		previousKey := fmt.Sprintf("ratelimit:%s:%d", key, windowStart.Add(-window).Unix())
+
+		var currentCountCmd *redis.IntCmd
+		var previousCountCmd *redis.StringCmd
+
+		_, err := redisRateLimitStore.client.TxPipelined(ctx, func(pipeliner redis.Pipeliner) error {
+			currentCountCmd = pipeliner.IncrBy(ctx, currentKey, 1)
+			pipeliner.Expire(ctx, currentKey, 2*window)
+			previousCountCmd = pipeliner.Get(ctx, previousKey)
+
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		currentCount := float64(currentCountCmd.Val())
+		previousCount, _ := previousCountCmd.Float64()
+
+		overlap := 1 - float64(now.Sub(windowStart))/float64(window)
+		count := previousCount*overlap + currentCount
+		allowed := count <= limit
+
+		if !allowed {
+			currentCount--
+
+			if err := redisRateLimitStore.client.DecrBy(ctx, currentKey, 1).Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		// The previous window keeps weighing less, until the current one
+		// becomes the previous one.
+		retryAfter := windowStart.Add(window).Sub(now)
+
+		if previousCount > 0 && currentCount+1 <= limit {
+			retryAfter = windowStart.Add(time.Duration((1 - (limit-currentCount-1)/previousCount) * float64(window))).Sub(now)
+		}
+
+		return RateLimit{
+			Allowed:    allowed,
+			Limit:      int(limit),
+			Remaining:  max(int(limit-count), 0),
+			Reset:      windowStart.Add(2 * window),
+			RetryAfter: max(retryAfter, 0),
+		}, nil
+	})
+
+	if err != nil {
+		return RateLimit{}, err
+	}
+
+	return rateLimit.(RateLimit), nil
+}
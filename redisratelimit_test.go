@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newMiniredisRateLimitStore returns a RedisRateLimitStore backed by an in
+// memory Redis server, which is closed at the end of the test.
+func newMiniredisRateLimitStore(t *testing.T) (*RedisRateLimitStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	miniredisServer := miniredis.RunT(t)
+	redisRateLimitStore, err := newRedisRateLimitStore("redis://" + miniredisServer.Addr())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { redisRateLimitStore.client.Close() })
+
+	return redisRateLimitStore, miniredisServer
+}
+
+func TestRedisRateLimitStoreAllow(t *testing.T) {
+	redisRateLimitStore, miniredisServer := newMiniredisRateLimitStore(t)
+
+	// The window is long enough for the test not to cross into the next one.
+	for index, want := range []bool{true, true, false} {
+		rateLimit, err := redisRateLimitStore.allow("client", 2, 24*time.Hour)
+
+		if err != nil {
+			t.Fatalf("allow() request %d error = %v", index+1, err)
+		}
+
+		if rateLimit.Allowed != want {
+			t.Errorf("allow() request %d = %t, want %t", index+1, rateLimit.Allowed, want)
+		}
+	}
+
+	keys := miniredisServer.Keys()
+
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "ratelimit:client:") {
+		t.Fatalf("keys = %v, want the counter of client", keys)
+	}
+
+	// The rejected request is not counted.
+	if count, _ := miniredisServer.Get(keys[0]); count != "2" {
+		t.Errorf("count = %s, want 2", count)
+	}
+
+	if ttl := miniredisServer.TTL(keys[0]); ttl != 48*time.Hour {
+		t.Errorf("TTL = %s, want %s", ttl, 48*time.Hour)
+	}
+}
+
+func TestRateLimiterSharesRedis(t *testing.T) {
+	redisRateLimitStore, _ := newMiniredisRateLimitStore(t)
+
+	// Two instances of the service, whose requests count against the same
+	// limit. At 1 request per hour, the window is 2 hours long.
+	rateLimiters := []*RateLimiter{newRateLimiter(0, 2), newRateLimiter(0, 2)}
+
+	for _, rateLimiter := range rateLimiters {
+		rateLimiter.rps = 1.0 / 3600
+		rateLimiter.redisRateLimitStore = redisRateLimitStore
+	}
+
+	for index, want := range []bool{true, true, false} {
+		if rateLimit := rateLimiters[index%2].allow("client"); rateLimit.Allowed != want {
+			t.Errorf("allow() request %d = %t, want %t", index+1, rateLimit.Allowed, want)
+		}
+	}
+}
+
+func TestRateLimiterFallsBackWithoutRedis(t *testing.T) {
+	redisRateLimitStore, miniredisServer := newMiniredisRateLimitStore(t)
+	miniredisServer.Close()
+
+	rateLimiter := newRateLimiter(1, 2)
+	rateLimiter.redisRateLimitStore = redisRateLimitStore
+
+	for index, want := range []bool{true, true, false} {
+		if rateLimit := rateLimiter.allow("client"); rateLimit.Allowed != want {
+			t.Errorf("allow() request %d = %t, want %t", index+1, rateLimit.Allowed, want)
+		}
+	}
+}
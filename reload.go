@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// minReloadInterval keeps repeated SIGHUPs from rereading the config file
+// over and over.
+const minReloadInterval = 5 * time.Second
+
+// restartConfigFields are built into the middleware chain by NewServer, so
+// that changing them only takes effect after a restart.
+var restartConfigFields = []string{
+	"MaxResponseBytes", "DevMode", "SimulatedLatency", "SimulatedLatencyJitter", "ServerHeader", "DeprecatedPaths", "DeprecationLink", "DeprecationDate", "SunsetDate",
+	"RateLimitRPS", "RateLimitBurst", "APIKeyRateLimitRPS", "APIKeyRateLimitBurst", "RedisURL", "AbuseWindows",
+	"WarmupDuration", "MirrorEndpoint", "MirrorWorkers", "ProxyUpstream", "ProxyPercentage", "TLDRoutes",
+	"ReputationAPIURL", "ReputationTimeout",
+	"ResponseHMACSecret", "AdminToken", "CooccurrenceResetHours",
+	"TransactionLogFile", "TransactionLogMaxBytes",
+}
+
+// secretConfigFields are logged as changed without their values, and on
+// startup as "[redacted]".
+var secretConfigFields = []string{"RedisURL", "ResponseHMACSecret", "AdminToken"}
+
+var logLevelNames = map[int]string{
+	logLevelDebug: "debug",
+	logLevelInfo:  "info",
+	logLevelWarn:  "warn",
+	logLevelError: "error",
+}
+
+// logStartup logs every field of config as key=value on startup, along with
+// what is not part of it, so that the log tells which configuration runs.
+func (server *Server) logStartup(listenUrl string, tls bool) {
+	logInfof("Starting listen=%s tls=%t logLevel=%s", listenUrl, tls, logLevelNames[logLevel])
+
+	value := reflect.ValueOf(*server.config())
+	fields := make([]string, 0, value.NumField())
+
+	for index := range value.NumField() {
+		name := value.Type().Field(index).Name
+		fieldValue := value.Field(index).Interface()
+
+		switch {
+		case slices.Contains(secretConfigFields, name) && !value.Field(index).IsZero():
+			fieldValue = "[redacted]"
+		case value.Field(index).Type() == reflect.TypeFor[*url.URL]() && !value.Field(index).IsNil():
+			// Userinfo may hold a password.
+			fieldValue = fieldValue.(*url.URL).Redacted()
+		}
+
+		fields = append(fields, fmt.Sprintf("%s=%+v", name, fieldValue))
+	}
+
+	logInfof("Config %s", strings.Join(fields, " "))
+	logInfof("Middleware chain, outermost first: %s", strings.Join(server.middlewares, " > "))
+}
+
+// reloadConfig rereads CONFIG_FILE and the environment and replaces the
+// configuration of the server, logging what changed. An invalid config file
+// keeps the current configuration.
+func (server *Server) reloadConfig() error {
+	if configFile := getEnv("CONFIG_FILE", ""); configFile != "" {
+		if err := loadConfigFile(configFile); err != nil {
+			return err
+		}
+	}
+
+	oldConfig := server.config()
+	newConfig := loadConfig()
+
+	// Only known once the TLS certificate is loaded on startup.
+	newConfig.TLSMinVersion = oldConfig.TLSMinVersion
+
+	// Not configurable, but given to NewServer, e.g. by tests.
+	newConfig.LookupOptions.Registry = oldConfig.LookupOptions.Registry
+
+	logConfigChanges(*oldConfig, newConfig)
+
+	server.configPointer.Store(&newConfig)
+
+	return nil
+}
+
+func logConfigChanges(oldConfig Config, newConfig Config) {
+	oldValue := reflect.ValueOf(oldConfig)
+	newValue := reflect.ValueOf(newConfig)
+	changes := 0
+
+	for index := range oldValue.NumField() {
+		name := oldValue.Type().Field(index).Name
+		oldFieldValue := oldValue.Field(index).Interface()
+		newFieldValue := newValue.Field(index).Interface()
+
+		if reflect.DeepEqual(oldFieldValue, newFieldValue) {
+			continue
+		}
+
+		changes++
+
+		suffix := ""
+
+		if slices.Contains(restartConfigFields, name) {
+			suffix = ", effective after a restart"
+		}
+
+		if slices.Contains(secretConfigFields, name) {
+			logInfof("Config %s changed%s", name, suffix)
+		} else {
+			logInfof("Config %s changed from %+v to %+v%s", name, oldFieldValue, newFieldValue, suffix)
+		}
+	}
+
+	if changes == 0 {
+		logInfof("Config reloaded without changes")
+	}
+}
+
+// reloadConfigOnSIGHUP reloads the configuration whenever the process
+// receives SIGHUP, e.g. from `kill -HUP`, at most once per minReloadInterval.
+func (server *Server) reloadConfigOnSIGHUP() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	var lastReload time.Time
+
+	for range signals {
+		if time.Since(lastReload) < minReloadInterval {
+			logWarnf("Ignoring SIGHUP, the config was reloaded less than %s ago", minReloadInterval)
+			continue
+		}
+
+		lastReload = time.Now()
+
+		if err := server.reloadConfig(); err != nil {
+			logErrorf("Reloading config failed, keeping the current one: %s", err)
+		}
+	}
+}
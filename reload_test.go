@@ -0,0 +1,59 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloadConfigOnSIGHUP(t *testing.T) {
+	robotsTxtFile := filepath.Join(t.TempDir(), "robots.txt")
+
+	if err := os.WriteFile(robotsTxtFile, []byte("User-agent: reloaded\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(loadConfig())
+	httptestServer := httptest.NewServer(server)
+	defer httptestServer.Close()
+
+	// Until reloadConfigOnSIGHUP is listening, SIGHUP would terminate the
+	// test binary.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	go server.reloadConfigOnSIGHUP()
+
+	t.Setenv("ROBOTS_TXT_FILE", robotsTxtFile)
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		httpResponse, err := httptestServer.Client().Get(httptestServer.URL + "/robots.txt")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(httpResponse.Body)
+		httpResponse.Body.Close()
+
+		if string(body) == "User-agent: reloaded\n" {
+			return
+		}
+	}
+
+	t.Error("robots.txt is unchanged after SIGHUP")
+}
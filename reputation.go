@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sony/gobreaker"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// unavailableReputationScore marks lookups whose reputation could not be
+// fetched, as scores themselves are never negative.
+const unavailableReputationScore = -1
+
+// ReputationApiResponse is what the reputation API is expected to answer to
+// `GET <REPUTATION_API_URL>?domain=example.com`.
+type ReputationApiResponse struct {
+	Score  float64 `json:"score"`
+	Source string  `json:"source"`
+}
+
+// Reputation fetches reputation scores of domains from an external API, e.g.
+// an adapter in front of VirusTotal or URLhaus. A circuit breaker stops
+// calling the API while it keeps failing, so that lookups do not wait on it.
+//
+// See: https://learn.microsoft.com/en-us/azure/architecture/patterns/circuit-breaker
+type Reputation struct {
+	apiUrl         string
+	httpClient     *http.Client
+	circuitBreaker *gobreaker.CircuitBreaker
+}
+
+func newReputation(apiUrl string, timeout time.Duration) *Reputation {
+	return &Reputation{
+		apiUrl:     apiUrl,
+		httpClient: newOutboundHttpClient(timeout),
+		circuitBreaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name: "reputation",
+			OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+				logWarnf("Circuit breaker %s changed from %s to %s", name, from, to)
+			},
+			// A client going away is no failure of the API.
+			IsSuccessful: func(err error) bool {
+				return err == nil || errors.Is(err, context.Canceled)
+			},
+		}),
+	}
+}
+
+func (reputation *Reputation) fetch(ctx context.Context, domain string) (ReputationApiResponse, error) {
+	reputationApiResponse, err := reputation.circuitBreaker.Execute(func() (any, error) {
+		httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, reputation.apiUrl+"?"+url.Values{"domain": {domain}}.Encode(), nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		httpResponse, err := reputation.httpClient.Do(httpRequest)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer httpResponse.Body.Close()
+
+		if httpResponse.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d", httpResponse.StatusCode)
+		}
+
+		reputationApiResponse := ReputationApiResponse{}
+
+		if err := json.NewDecoder(httpResponse.Body).Decode(&reputationApiResponse); err != nil {
+			return nil, fmt.Errorf("malformed response: %w", err)
+		}
+
+		return reputationApiResponse, nil
+	})
+
+	if err != nil {
+		return ReputationApiResponse{}, err
+	}
+
+	return reputationApiResponse.(ReputationApiResponse), nil
+}
+
+// addTo sets the reputation of the domain of result, or marks it as
+// unavailable.
+func (reputation *Reputation) addTo(ctx context.Context, result *publicsuffix.Result) {
+	reputationApiResponse, err := reputation.fetch(ctx, result.Domain)
+
+	if err != nil {
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			logDebugf("Skipping reputation of %s: %s", result.Domain, err)
+		} else {
+			logWarnf("Fetching reputation of %s failed: %s", result.Domain, err)
+		}
+
+		reputationScore := float64(unavailableReputationScore)
+		result.ReputationScore = &reputationScore
+		result.ReputationUnavailable = true
+
+		return
+	}
+
+	result.ReputationScore = &reputationApiResponse.Score
+	result.ReputationSource = reputationApiResponse.Source
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var errResponseTooLarge = errors.New("response is too large")
+
+// limitedHttpResponseWriter counts the bytes of a response and refuses to
+// write more than maxBytes. The status code is held back until the first
+// write, so that a response exceeding the limit at once, as encoded JSON
+// does, can still be replaced by an error.
+type limitedHttpResponseWriter struct {
+	http.ResponseWriter
+	server     *Server
+	maxBytes   int64
+	written    int64
+	statusCode int
+	headerSent bool
+	exceeded   bool
+
+	// aborted is set when the limit is exceeded after the headers were sent.
+	aborted bool
+}
+
+func (httpResponseWriter *limitedHttpResponseWriter) WriteHeader(statusCode int) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = statusCode
+	}
+}
+
+func (httpResponseWriter *limitedHttpResponseWriter) sendHeader() {
+	if httpResponseWriter.headerSent {
+		return
+	}
+
+	httpResponseWriter.headerSent = true
+
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = http.StatusOK
+	}
+
+	httpResponseWriter.ResponseWriter.WriteHeader(httpResponseWriter.statusCode)
+}
+
+func (httpResponseWriter *limitedHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.exceeded {
+		return 0, errResponseTooLarge
+	}
+
+	if httpResponseWriter.written+int64(len(data)) > httpResponseWriter.maxBytes {
+		httpResponseWriter.exceeded = true
+
+		if httpResponseWriter.headerSent {
+			httpResponseWriter.aborted = true
+		} else {
+			httpResponseWriter.headerSent = true
+
+			// The headers of the handler describe the response it intended.
+			httpResponseWriter.Header().Del("Content-Length")
+			httpResponseWriter.Header().Del("Content-Disposition")
+			httpResponseWriter.Header().Del("Cache-Control")
+
+			httpResponseWriter.server.writeErrorHttpResponse(httpResponseWriter.ResponseWriter, http.StatusInsufficientStorage, ErrorIdResponseTooLarge, fmt.Sprintf("Response exceeds the maximum of %d bytes, look up fewer domains at once", httpResponseWriter.maxBytes))
+		}
+
+		return 0, errResponseTooLarge
+	}
+
+	httpResponseWriter.sendHeader()
+	httpResponseWriter.written += int64(len(data))
+
+	return httpResponseWriter.ResponseWriter.Write(data)
+}
+
+func (httpResponseWriter *limitedHttpResponseWriter) FlushError() error {
+	if httpResponseWriter.exceeded {
+		return errResponseTooLarge
+	}
+
+	httpResponseWriter.sendHeader()
+
+	return http.NewResponseController(httpResponseWriter.ResponseWriter).Flush()
+}
+
+func (httpResponseWriter *limitedHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+// responseLimitMiddleware caps the size of response bodies at maxBytes, as
+// many small domains can add up to a huge batch response. A response that is
+// already streaming when it exceeds the limit is aborted instead.
+func (server *Server) responseLimitMiddleware(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		limitedHttpResponseWriter := &limitedHttpResponseWriter{
+			ResponseWriter: httpResponseWriter,
+			server:         server,
+			maxBytes:       maxBytes,
+		}
+
+		next.ServeHTTP(limitedHttpResponseWriter, httpRequest)
+
+		if !limitedHttpResponseWriter.exceeded {
+			limitedHttpResponseWriter.sendHeader()
+			return
+		}
+
+		logWarnf("Response to %s %s exceeded MAX_RESPONSE_BYTES=%d", httpRequest.Method, httpRequest.URL.Path, maxBytes)
+
+		if limitedHttpResponseWriter.aborted {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// initSentry reports panics and 5xx errors to the Sentry, or Sentry
+// compatible, project of dsn. Without calling it, the sentry.Capture*
+// functions are no-ops.
+//
+// See: https://docs.sentry.io/platforms/go/
+func initSentry(dsn string) {
+	if err := sentry.Init(sentry.ClientOptions{Dsn: dsn}); err != nil {
+		logWarnf("ignoring invalid SENTRY_DSN: %s", err)
+	}
+}
+
+// recoveryMiddleware answers with a 500 error instead of dropping the
+// connection when a handler panics, and reports the panic. Handlers abort a
+// response they already started on purpose with http.ErrAbortHandler.
+func (server *Server) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		defer func() {
+			recovered := recover()
+
+			if recovered == nil {
+				return
+			}
+
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err, ok := recovered.(error)
+
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+
+			sentry.CaptureException(err)
+
+			server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, fmt.Errorf("panic: %w", err))
+		}()
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// Config holds everything a Server needs to know. Its zero value is usable
+// but differs from the defaults of loadConfig, e.g. in JSONEscapeHTML.
+type Config struct {
+	LookupOptions  publicsuffix.Options
+	BatchWorkers   int
+	UploadMaxBytes int
+
+	// WarmupDuration is how long identical lookups are coalesced after
+	// startup.
+	WarmupDuration time.Duration
+
+	// MaxResponseBytes caps the size of response bodies when positive.
+	MaxResponseBytes int
+
+	// JSONEscapeHTML keeps the encoding/json default of escaping `<`, `>` and
+	// `&` as `\u003c`, `\u003e` and `\u0026`.
+	JSONEscapeHTML bool
+
+	ServerHeader    string
+	DeprecatedPaths []string
+	DeprecationLink string
+
+	// DeprecatedPaths are answered with 410 Gone from SunsetDate on, unless
+	// it is zero. DeprecationDate is sent along when it is not.
+	DeprecationDate time.Time
+	SunsetDate      time.Time
+	FaviconPath     string
+	RobotsTxtFile   string
+
+	// Requests are rate limited per IP address when RateLimitRPS is positive
+	// and per API key when APIKeyRateLimitRPS is.
+	RateLimitRPS         int
+	RateLimitBurst       int
+	APIKeyRateLimitRPS   int
+	APIKeyRateLimitBurst int
+
+	// RedisURL shares the rate limits between instances when set.
+	RedisURL string
+
+	// AbuseWindows block IP addresses exceeding their request limit.
+	AbuseWindows []AbuseWindow
+
+	// RetryAfterSeconds is sent as Retry-After with 503 errors.
+	RetryAfterSeconds int
+
+	MirrorEndpoint string
+	MirrorWorkers  int
+
+	// ReputationAPIURL adds the reputation of the domain to lookups when set.
+	ReputationAPIURL  string
+	ReputationTimeout time.Duration
+
+	// ProxyPercentage percent of the lookups are answered by ProxyUpstream.
+	ProxyUpstream   *url.URL
+	ProxyPercentage int
+
+	// TLDRoutes are the backends lookups of domains with their TLD are
+	// forwarded to.
+	TLDRoutes map[string]*url.URL
+
+	// TemplateTimeout bounds the rendering of a page.
+	TemplateTimeout time.Duration
+
+	// DebugEndpoints adds diagnostics to lookups requested with
+	// `X-Debug: true`.
+	DebugEndpoints bool
+
+	// HandlerTimeout is the longest deadline a request may ask for.
+	HandlerTimeout time.Duration
+
+	// SimulatedLatency delays every request, by up to SimulatedLatencyJitter
+	// more or less, in DevMode only.
+	DevMode                bool
+	SimulatedLatency       time.Duration
+	SimulatedLatencyJitter time.Duration
+
+	// DisplayLocation is the time zone dates are shown in on pages.
+	DisplayLocation *time.Location
+
+	// ResponseHMACSecret signs successful responses when set.
+	ResponseHMACSecret string
+
+	// AdminToken authorizes requests to /admin/, which is disabled without.
+	AdminToken             string
+	CooccurrenceResetHours int
+
+	// CacheMaxAge is the `Cache-Control` max-age in seconds of a lookup by
+	// its isManagedBy value, as ICANN suffixes change far less often than
+	// private ones.
+	CacheMaxAge map[string]int
+
+	// TransactionLogFile records every request and response when set.
+	TransactionLogFile     string
+	TransactionLogMaxBytes int
+
+	// Only set when the server is started with a TLS certificate.
+	TLSMinVersion string
+}
+
+func loadConfig() Config {
+	config := Config{
+		LookupOptions: publicsuffix.Options{
+			MaxLabelCount: getEnvInt("DOMAIN_MAX_LABELS", publicsuffix.DefaultMaxLabelCount),
+			DNSTimeout:    time.Duration(getEnvInt("DNS_TIMEOUT_SECONDS", 3)) * time.Second,
+			DNSStaleGrace: time.Duration(getEnvInt("CACHE_STALE_GRACE_SECONDS", 30)) * time.Second,
+
+			AllowUnderscore: getEnv("ALLOW_UNDERSCORE", "false") == "true",
+			CheckDNSSEC:     getEnv("DNSSEC_CHECK", "false") == "true",
+		},
+		BatchWorkers:     getEnvInt("BATCH_WORKERS", 8),
+		WarmupDuration:   time.Duration(getEnvInt("WARMUP_DURATION_SECONDS", 5)) * time.Second,
+		UploadMaxBytes:   getEnvInt("UPLOAD_MAX_BYTES", 10<<20),
+		MaxResponseBytes: getEnvInt("MAX_RESPONSE_BYTES", 10<<20),
+		JSONEscapeHTML:   getEnv("JSON_ESCAPE_HTML", "true") != "false",
+		ServerHeader:     getEnv("SERVER_HEADER", "publicsuffix-service"),
+		DeprecatedPaths:  getEnvList("DEPRECATED_PATHS", ""),
+		DeprecationLink:  getEnv("DEPRECATION_LINK", ""),
+		FaviconPath:      getEnv("FAVICON_PATH", ""),
+		RobotsTxtFile:    getEnv("ROBOTS_TXT_FILE", ""),
+
+		RateLimitRPS:         getEnvInt("RATE_LIMIT_RPS", 0),
+		RateLimitBurst:       getEnvInt("RATE_LIMIT_BURST", 20),
+		APIKeyRateLimitRPS:   getEnvInt("API_KEY_RATE_LIMIT_RPS", 0),
+		APIKeyRateLimitBurst: getEnvInt("API_KEY_RATE_LIMIT_BURST", 200),
+		RedisURL:             getEnv("REDIS_URL", ""),
+		RetryAfterSeconds:    getEnvInt("RETRY_AFTER_SECONDS", 1),
+
+		AbuseWindows: []AbuseWindow{
+			{Name: "ABUSE_1MIN", Duration: time.Minute, Limit: getEnvInt("ABUSE_1MIN", 0)},
+			{Name: "ABUSE_5MIN", Duration: 5 * time.Minute, Limit: getEnvInt("ABUSE_5MIN", 0)},
+			{Name: "ABUSE_60MIN", Duration: time.Hour, Limit: getEnvInt("ABUSE_60MIN", 0)},
+		},
+
+		MirrorEndpoint: getEnv("MIRROR_ENDPOINT", ""),
+		MirrorWorkers:  getEnvInt("MIRROR_WORKERS", 4),
+
+		ReputationAPIURL:  getEnv("REPUTATION_API_URL", ""),
+		ReputationTimeout: time.Duration(getEnvInt("REPUTATION_TIMEOUT_SECONDS", 2)) * time.Second,
+
+		ProxyPercentage: getEnvInt("PROXY_PERCENTAGE", 0),
+
+		TemplateTimeout: time.Duration(getEnvInt("TEMPLATE_TIMEOUT_SECONDS", 5)) * time.Second,
+		HandlerTimeout:  time.Duration(getEnvInt("HANDLER_TIMEOUT_SECONDS", 30)) * time.Second,
+		DebugEndpoints:  getEnv("DEBUG_ENDPOINTS", "false") == "true",
+
+		DevMode:                getEnv("DEV_MODE", "false") == "true",
+		SimulatedLatency:       time.Duration(getEnvInt("SIMULATED_LATENCY_MS", 0)) * time.Millisecond,
+		SimulatedLatencyJitter: time.Duration(getEnvInt("SIMULATED_LATENCY_JITTER_MS", 0)) * time.Millisecond,
+
+		ResponseHMACSecret: getEnv("RESPONSE_HMAC_SECRET", ""),
+
+		AdminToken:             getEnv("ADMIN_TOKEN", ""),
+		CooccurrenceResetHours: getEnvInt("COOCCURRENCE_RESET_HOURS", 24),
+
+		TransactionLogFile:     getEnv("TRANSACTION_LOG_FILE", ""),
+		TransactionLogMaxBytes: getEnvInt("TRANSACTION_LOG_MAX_BYTES", 100<<20),
+
+		CacheMaxAge: map[string]int{
+			publicsuffix.ManagedByIcann:         getEnvInt("CACHE_MAX_AGE_ICANN", 86400),
+			publicsuffix.ManagedByPrivateEntity: getEnvInt("CACHE_MAX_AGE_PRIVATE", 3600),
+			publicsuffix.ManagedByNone:          getEnvInt("CACHE_MAX_AGE_NONE", 300),
+		},
+	}
+
+	config.DisplayLocation = time.UTC
+
+	if displayTimezone := getEnv("DISPLAY_TIMEZONE", ""); displayTimezone != "" {
+		displayLocation, err := time.LoadLocation(displayTimezone)
+
+		if err != nil {
+			logWarnf("ignoring invalid DISPLAY_TIMEZONE, falling back to UTC: %s", err)
+		} else {
+			config.DisplayLocation = displayLocation
+		}
+	}
+
+	if deprecationDate := getEnv("DEPRECATION_DATE", ""); deprecationDate != "" {
+		var err error
+
+		if config.DeprecationDate, err = time.Parse(time.DateOnly, deprecationDate); err != nil {
+			logWarnf("ignoring invalid DEPRECATION_DATE, expected YYYY-MM-DD: %s", err)
+		}
+	}
+
+	if sunsetDate := getEnv("SUNSET_DATE", ""); sunsetDate != "" {
+		var err error
+
+		if config.SunsetDate, err = time.Parse(time.DateOnly, sunsetDate); err != nil {
+			logWarnf("ignoring invalid SUNSET_DATE, expected YYYY-MM-DD: %s", err)
+		}
+	}
+
+	if tldRouteMap := getEnv("TLD_ROUTE_MAP", ""); tldRouteMap != "" {
+		var err error
+
+		if config.TLDRoutes, err = parseTLDRoutes(tldRouteMap); err != nil {
+			logWarnf("ignoring invalid TLD_ROUTE_MAP: %s", err)
+		}
+	}
+
+	if proxyUpstream := getEnv("PROXY_UPSTREAM", ""); proxyUpstream != "" {
+		var err error
+
+		if config.ProxyUpstream, err = url.Parse(proxyUpstream); err != nil {
+			logWarnf("ignoring invalid PROXY_UPSTREAM: %s", err)
+		}
+	}
+
+	return config
+}
+
+// Server serves the web service. Unlike the http.DefaultServeMux it routes on
+// its own mux, so that tests can wrap it in an httptest.Server.
+type Server struct {
+	// configPointer is swapped as a whole when the configuration is reloaded,
+	// see reloadConfig.
+	configPointer atomic.Pointer[Config]
+	mirror        *Mirror
+	reputation    *Reputation
+	cooccurrence  *Cooccurrence
+	handler       http.Handler
+
+	// middlewares names the middleware chain of handler, outermost first.
+	middlewares []string
+
+	// lookupCoalescer is nil when lookups are never coalesced.
+	lookupCoalescer *LookupCoalescer
+
+	// inFlightRequests counts the requests being handled, which shutting
+	// down waits for.
+	inFlightRequests atomic.Int64
+}
+
+func NewServer(config Config) *Server {
+	server := &Server{
+		cooccurrence: newCooccurrence(time.Duration(config.CooccurrenceResetHours) * time.Hour),
+	}
+
+	server.configPointer.Store(&config)
+
+	if config.MirrorEndpoint != "" {
+		server.mirror = startMirror(config.MirrorEndpoint, config.MirrorWorkers)
+	}
+
+	if config.ReputationAPIURL != "" {
+		server.reputation = newReputation(config.ReputationAPIURL, config.ReputationTimeout)
+	}
+
+	if config.WarmupDuration > 0 {
+		server.lookupCoalescer = newLookupCoalescer(config.WarmupDuration)
+	}
+
+	serveMux := http.NewServeMux()
+
+	// Static
+	serveMux.Handle("/static/", headAwareHandler(gzipFileServer(embededStaticFileSystem)))
+	serveMux.Handle("/favicon.ico", headAwareHandler(http.HandlerFunc(server.faviconHttpHandler)))
+	serveMux.Handle("/robots.txt", headAwareHandler(http.HandlerFunc(server.robotsTxtHttpHandler)))
+	serveMux.Handle("/sitemap.xml", headAwareHandler(http.HandlerFunc(server.sitemapXmlHttpHandler)))
+
+	// Dynamic
+	var publicSuffixHttpHandler http.Handler = http.HandlerFunc(server.publicSuffixHttpHandler)
+
+	if config.ProxyUpstream != nil && config.ProxyPercentage > 0 {
+		publicSuffixHttpHandler = server.proxyHandler(config.ProxyUpstream, config.ProxyPercentage, publicSuffixHttpHandler)
+	}
+
+	if len(config.TLDRoutes) > 0 {
+		publicSuffixHttpHandler = server.tldRouteHandler(config.TLDRoutes, publicSuffixHttpHandler)
+	}
+
+	serveMux.Handle("/publicsuffix", headAwareHandler(publicSuffixHttpHandler))
+	serveMux.Handle("/publicsuffix/batch", noKeepAliveMiddleware(acceptsStreamedBatch, headAwareHandler(server.deduplicationHandler(newDeduplicator(deduplicationWindow), http.HandlerFunc(server.batchHttpHandler)))))
+	serveMux.Handle("/publicsuffix/batch/upload", noKeepAliveMiddleware(nil, http.HandlerFunc(server.batchUploadHttpHandler)))
+	serveMux.Handle("/migrate", headAwareHandler(http.HandlerFunc(server.migrateHttpHandler)))
+	serveMux.Handle("/history", headAwareHandler(http.HandlerFunc(server.historyHttpHandler)))
+	serveMux.Handle("/suffixlist/diff", headAwareHandler(http.HandlerFunc(server.suffixListDiffHttpHandler)))
+	serveMux.Handle("/similarity", headAwareHandler(http.HandlerFunc(server.similarityHttpHandler)))
+	serveMux.Handle("/tree", headAwareHandler(http.HandlerFunc(server.treeHttpHandler)))
+	serveMux.Handle("/tld/", headAwareHandler(http.HandlerFunc(server.tldHttpHandler)))
+	serveMux.Handle("/health", headAwareHandler(http.HandlerFunc(server.healthHttpHandler)))
+	serveMux.Handle("/version", headAwareHandler(http.HandlerFunc(server.versionHttpHandler)))
+	serveMux.Handle("/metrics", headAwareHandler(promhttp.Handler()))
+
+	// Admin
+	serveMux.Handle("/admin/cooccurrence", server.adminHandler(config.AdminToken, headAwareHandler(http.HandlerFunc(server.cooccurrenceHttpHandler))))
+
+	// Redirects
+	serveMux.HandleFunc("/github", redirectHttpHandler("https://github.com/stefankuehnel/publicsuffix.stefan-dev.de"))
+
+	// Templates
+	serveMux.Handle("/", headAwareHandler(http.HandlerFunc(server.indexHttpHandler)))
+
+	var handler http.Handler = serveMux
+
+	chain := func(middleware string) {
+		server.middlewares = slices.Insert(server.middlewares, 0, middleware)
+	}
+
+	if config.DevMode && (config.SimulatedLatency > 0 || config.SimulatedLatencyJitter > 0) {
+		handler = latencyMiddleware(config.SimulatedLatency, config.SimulatedLatencyJitter, handler)
+		chain("latency")
+	}
+
+	handler = server.timeoutMiddleware(handler)
+	chain("timeout")
+
+	if config.MaxResponseBytes > 0 {
+		handler = server.responseLimitMiddleware(int64(config.MaxResponseBytes), handler)
+		chain("responseLimit")
+	}
+
+	handler = server.recoveryMiddleware(server.jsonErrorMiddleware(handler))
+	chain("jsonError")
+	chain("recovery")
+
+	if config.ResponseHMACSecret != "" {
+		handler = signatureMiddleware([]byte(config.ResponseHMACSecret), handler)
+		chain("signature")
+	}
+
+	var ipRateLimiter, apiKeyRateLimiter *RateLimiter
+
+	if config.RateLimitRPS > 0 {
+		ipRateLimiter = newRateLimiter(config.RateLimitRPS, config.RateLimitBurst)
+	}
+
+	if config.APIKeyRateLimitRPS > 0 {
+		apiKeyRateLimiter = newRateLimiter(config.APIKeyRateLimitRPS, config.APIKeyRateLimitBurst)
+	}
+
+	if config.RedisURL != "" && (ipRateLimiter != nil || apiKeyRateLimiter != nil) {
+		redisRateLimitStore, err := newRedisRateLimitStore(config.RedisURL)
+
+		if err != nil {
+			logWarnf("ignoring invalid REDIS_URL: %s", err)
+		} else {
+			if ipRateLimiter != nil {
+				ipRateLimiter.redisRateLimitStore = redisRateLimitStore
+			}
+
+			if apiKeyRateLimiter != nil {
+				apiKeyRateLimiter.redisRateLimitStore = redisRateLimitStore
+				apiKeyRateLimiter.redisKeyPrefix = "apikey:"
+			}
+		}
+	}
+
+	if ipRateLimiter != nil || apiKeyRateLimiter != nil {
+		handler = server.rateLimitMiddleware(ipRateLimiter, apiKeyRateLimiter, handler)
+		chain("rateLimit")
+	}
+
+	for _, abuseWindow := range config.AbuseWindows {
+		if abuseWindow.Limit > 0 {
+			handler = server.abuseMiddleware(newAbuseDetector(config.AbuseWindows), handler)
+			chain("abuse")
+			break
+		}
+	}
+
+	if config.TLSMinVersion != "" {
+		handler = server.tlsVersionMiddleware(tlsMinVersions[config.TLSMinVersion], handler)
+		chain("tlsVersion")
+	}
+
+	handler = server.localeMiddleware(loadTranslations(), handler)
+	chain("locale")
+	handler = statisticsMiddleware(handler)
+	chain("statistics")
+	handler = securityHeadersMiddleware(config.ServerHeader, handler)
+	chain("securityHeaders")
+
+	if len(config.DeprecatedPaths) > 0 {
+		handler = server.deprecationMiddleware(config.DeprecatedPaths, config.DeprecationLink, config.DeprecationDate, config.SunsetDate, handler)
+		chain("deprecation")
+	}
+
+	if config.TransactionLogFile != "" {
+		transactionLog, err := openTransactionLog(config.TransactionLogFile, int64(config.TransactionLogMaxBytes))
+
+		if err != nil {
+			logErrorf("Opening TRANSACTION_LOG_FILE failed, transactions are not logged: %s", err)
+		} else {
+			handler = transactionLogMiddleware(transactionLog, handler)
+			chain("transactionLog")
+		}
+	}
+
+	server.handler = handler
+
+	return server
+}
+
+// config returns the current configuration, which a reload may replace
+// between two calls.
+func (server *Server) config() *Config {
+	return server.configPointer.Load()
+}
+
+func (server *Server) ServeHTTP(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	server.inFlightRequests.Add(1)
+	defer server.inFlightRequests.Add(-1)
+
+	server.handler.ServeHTTP(httpResponseWriter, httpRequest)
+}
+
+// drain waits until no request is in flight anymore or ctx is done, and
+// returns the number of requests still in flight. http.Server.Shutdown gives
+// up on handlers once its context is done, but does not stop them.
+func (server *Server) drain(ctx context.Context) int64 {
+	for {
+		inFlightRequests := server.inFlightRequests.Load()
+
+		if inFlightRequests == 0 {
+			return 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return inFlightRequests
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (server *Server) newJSONEncoder(writer io.Writer) *json.Encoder {
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(server.config().JSONEscapeHTML)
+
+	return encoder
+}
+
+type ErrorHttpResponse struct {
+	ErrorCode    int    `json:"errorCode"`
+	ErrorType    string `json:"errorType"`
+	ErrorId      string `json:"errorId"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func (server *Server) writeErrorHttpResponse(httpResponseWriter http.ResponseWriter, statusCode int, errorId string, errorMessage string) {
+	// Every overloaded or unavailable part of the service asks for the same
+	// back-off.
+	if statusCode == http.StatusServiceUnavailable && httpResponseWriter.Header().Get("Retry-After") == "" {
+		setRetryAfterHeader(httpResponseWriter, time.Duration(server.config().RetryAfterSeconds)*time.Second)
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		sentry.CaptureMessage(fmt.Sprintf("%s: %s", errorId, errorMessage))
+	}
+
+	errorType := http.StatusText(statusCode)
+
+	if translation := translationOf(httpResponseWriter); translation != nil {
+		errorType = translation.errorType(statusCode)
+	}
+
+	httpResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	httpResponseWriter.WriteHeader(statusCode)
+
+	server.newJSONEncoder(httpResponseWriter).Encode(ErrorHttpResponse{
+		ErrorCode:    statusCode,
+		ErrorType:    errorType,
+		ErrorId:      errorId,
+		ErrorMessage: errorMessage,
+	})
+}
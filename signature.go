@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+)
+
+const responseSignatureHeader = "X-Response-Signature"
+
+// signingHttpResponseWriter holds back the response, as its signature header
+// can only be computed once the whole body is known. Once a handler flushes,
+// e.g. to stream a batch, the response is passed through instead and the
+// signature follows as a trailer.
+type signingHttpResponseWriter struct {
+	http.ResponseWriter
+	httpRequest *http.Request
+	statusCode  int
+	body        bytes.Buffer
+	mac         hash.Hash
+	streaming   bool
+}
+
+// signed reports whether the response gets a signature.
+func (httpResponseWriter *signingHttpResponseWriter) signed() bool {
+	// HEAD responses have no body to verify the signature against.
+	return httpResponseWriter.statusCode < http.StatusBadRequest && httpResponseWriter.httpRequest.Method != http.MethodHead
+}
+
+func (httpResponseWriter *signingHttpResponseWriter) WriteHeader(statusCode int) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = statusCode
+	}
+}
+
+func (httpResponseWriter *signingHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = http.StatusOK
+	}
+
+	httpResponseWriter.mac.Write(data)
+
+	if httpResponseWriter.streaming {
+		return httpResponseWriter.ResponseWriter.Write(data)
+	}
+
+	return httpResponseWriter.body.Write(data)
+}
+
+func (httpResponseWriter *signingHttpResponseWriter) FlushError() error {
+	if !httpResponseWriter.streaming {
+		httpResponseWriter.streaming = true
+
+		if httpResponseWriter.statusCode == 0 {
+			httpResponseWriter.statusCode = http.StatusOK
+		}
+
+		if httpResponseWriter.signed() {
+			httpResponseWriter.Header().Set("Trailer", responseSignatureHeader)
+		}
+
+		httpResponseWriter.ResponseWriter.WriteHeader(httpResponseWriter.statusCode)
+
+		if _, err := httpResponseWriter.ResponseWriter.Write(httpResponseWriter.body.Bytes()); err != nil {
+			return err
+		}
+
+		httpResponseWriter.body.Reset()
+	}
+
+	return http.NewResponseController(httpResponseWriter.ResponseWriter).Flush()
+}
+
+func (httpResponseWriter *signingHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+// signatureMiddleware signs successful responses with the hex encoded
+// HMAC-SHA256 of their body. Error responses are left unsigned, so that they
+// do not reveal timing information about the secret.
+func signatureMiddleware(secret []byte, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		signingHttpResponseWriter := &signingHttpResponseWriter{
+			ResponseWriter: httpResponseWriter,
+			httpRequest:    httpRequest,
+			mac:            hmac.New(sha256.New, secret),
+		}
+
+		next.ServeHTTP(signingHttpResponseWriter, httpRequest)
+
+		if signingHttpResponseWriter.statusCode == 0 {
+			signingHttpResponseWriter.statusCode = http.StatusOK
+		}
+
+		if signingHttpResponseWriter.signed() {
+			httpResponseWriter.Header().Set(responseSignatureHeader, hex.EncodeToString(signingHttpResponseWriter.mac.Sum(nil)))
+		}
+
+		// The headers of a streamed response are long sent, the signature
+		// above is its trailer.
+		if signingHttpResponseWriter.streaming {
+			return
+		}
+
+		if httpResponseWriter.Header().Get("Content-Length") == "" && httpRequest.Method != http.MethodHead {
+			httpResponseWriter.Header().Set("Content-Length", strconv.Itoa(signingHttpResponseWriter.body.Len()))
+		}
+
+		httpResponseWriter.WriteHeader(signingHttpResponseWriter.statusCode)
+		httpResponseWriter.Write(signingHttpResponseWriter.body.Bytes())
+	})
+}
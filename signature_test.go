@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignatureMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	handler := signatureMiddleware(secret, http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		httpResponseWriter.Write([]byte(`{"publicSuffix":"co.uk"}`))
+	}))
+
+	httpResponseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/publicsuffix?domain=example.co.uk", nil))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(httpResponseRecorder.Body.Bytes())
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if signature := httpResponseRecorder.Header().Get(responseSignatureHeader); signature != want {
+		t.Errorf("%s = %q, want %q", responseSignatureHeader, signature, want)
+	}
+}
+
+func TestSignatureMiddlewareSkipsErrors(t *testing.T) {
+	handler := signatureMiddleware([]byte("secret"), http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		http.Error(httpResponseWriter, "bad request", http.StatusBadRequest)
+	}))
+
+	httpResponseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/publicsuffix", nil))
+
+	if signature := httpResponseRecorder.Header().Get(responseSignatureHeader); signature != "" {
+		t.Errorf("%s = %q on an error response, want none", responseSignatureHeader, signature)
+	}
+}
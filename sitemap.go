@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// sitemapPaths are the pages search engines should index.
+var sitemapPaths = []string{"/", "/version"}
+
+type SitemapUrlSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	Urls    []SitemapUrl `xml:"url"`
+}
+
+type SitemapUrl struct {
+	Location     string `xml:"loc"`
+	LastModified string `xml:"lastmod,omitempty"`
+}
+
+// buildTime returns the commit time of the VCS revision the binary was built
+// from, if known.
+//
+// See: https://pkg.go.dev/runtime/debug#BuildSetting
+func buildTime() (time.Time, bool) {
+	buildInfo, ok := debug.ReadBuildInfo()
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	for _, buildSetting := range buildInfo.Settings {
+		if buildSetting.Key != "vcs.time" {
+			continue
+		}
+
+		vcsTime, err := time.Parse(time.RFC3339, buildSetting.Value)
+
+		return vcsTime, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+// sitemapXmlHttpHandler lists the pages of the web UI, with the URLs of the
+// host the sitemap was requested from.
+//
+// See: https://www.sitemaps.org/protocol.html
+func (server *Server) sitemapXmlHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	scheme := "http"
+
+	if httpRequest.TLS != nil || httpRequest.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	sitemapUrlSet := SitemapUrlSet{}
+
+	for _, path := range sitemapPaths {
+		sitemapUrl := SitemapUrl{Location: scheme + "://" + httpRequest.Host + path}
+
+		if lastModified, ok := buildTime(); ok {
+			sitemapUrl.LastModified = lastModified.UTC().Format(time.DateOnly)
+		}
+
+		sitemapUrlSet.Urls = append(sitemapUrlSet.Urls, sitemapUrl)
+	}
+
+	data, err := xml.MarshalIndent(sitemapUrlSet, "", "  ")
+
+	if err != nil {
+		server.writeInternalErrorHttpResponse(httpResponseWriter, httpRequest, err)
+		return
+	}
+
+	httpResponseWriter.Header().Set("Content-Type", "application/xml")
+	httpResponseWriter.Header().Set("Cache-Control", "public, max-age=86400")
+
+	httpResponseWriter.Write([]byte(xml.Header))
+	httpResponseWriter.Write(data)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// gzipFileServer serves the files of fileSystem like http.FileServer, but
+// prefers a pre-compressed `.gz` sidecar, e.g. `style.css.gz` for
+// `style.css`, when the client accepts gzip. The sidecars are generated by
+// `make generate`, so nothing is compressed at runtime.
+func gzipFileServer(fileSystem fs.FS) http.Handler {
+	fileServer := http.FileServer(http.FS(fileSystem))
+
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		httpResponseWriter.Header().Add("Vary", "Accept-Encoding")
+
+		name := strings.TrimPrefix(path.Clean(httpRequest.URL.Path), "/")
+
+		if !strings.Contains(httpRequest.Header.Get("Accept-Encoding"), "gzip") || strings.HasSuffix(name, ".gz") {
+			fileServer.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		if _, err := fs.Stat(fileSystem, name+".gz"); err != nil {
+			fileServer.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		// http.FileServer would derive the type from the `.gz` extension.
+		if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+			httpResponseWriter.Header().Set("Content-Type", contentType)
+		}
+
+		httpResponseWriter.Header().Set("Content-Encoding", "gzip")
+
+		gzipHttpRequest := httpRequest.Clone(httpRequest.Context())
+		gzipHttpRequest.URL.Path = "/" + name + ".gz"
+
+		fileServer.ServeHTTP(httpResponseWriter, gzipHttpRequest)
+	})
+}
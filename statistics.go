@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+var requestStatistics struct {
+	requests atomic.Uint64
+	errors   atomic.Uint64
+}
+
+// statusHttpResponseWriter records the status code written by a handler.
+type statusHttpResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (httpResponseWriter *statusHttpResponseWriter) WriteHeader(statusCode int) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = statusCode
+	}
+
+	httpResponseWriter.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (httpResponseWriter *statusHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = http.StatusOK
+	}
+
+	return httpResponseWriter.ResponseWriter.Write(data)
+}
+
+func (httpResponseWriter *statusHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+func statisticsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		statusHttpResponseWriter := &statusHttpResponseWriter{ResponseWriter: httpResponseWriter}
+
+		next.ServeHTTP(statusHttpResponseWriter, httpRequest)
+
+		requestStatistics.requests.Add(1)
+
+		if statusHttpResponseWriter.statusCode >= http.StatusBadRequest {
+			requestStatistics.errors.Add(1)
+		}
+	})
+}
+
+// startInfluxDBExport periodically pushes the request statistics to an
+// InfluxDB write endpoint, e.g. `https://influxdb:8086/api/v2/write?org=o&bucket=b`.
+//
+// See: https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+func startInfluxDBExport(influxDBUrl string, influxDBToken string, interval time.Duration) {
+	httpClient := newOutboundHttpClient(10 * time.Second)
+
+	go func() {
+		for range time.Tick(interval) {
+			line := fmt.Sprintf(
+				"publicsuffix requests=%du,errors=%du,cache_hits=%du %d\n",
+				requestStatistics.requests.Load(),
+				requestStatistics.errors.Load(),
+				publicsuffix.DNSCacheHits(),
+				time.Now().UnixNano(),
+			)
+
+			httpRequest, err := http.NewRequest(http.MethodPost, influxDBUrl, strings.NewReader(line))
+
+			if err != nil {
+				logErrorf("exporting statistics to InfluxDB failed: %s", err)
+				continue
+			}
+
+			httpRequest.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+			if influxDBToken != "" {
+				httpRequest.Header.Set("Authorization", "Token "+influxDBToken)
+			}
+
+			httpResponse, err := httpClient.Do(withClientTrace(httpRequest, "influxdb"))
+
+			if err != nil {
+				logErrorf("exporting statistics to InfluxDB failed: %s", err)
+				continue
+			}
+
+			httpResponse.Body.Close()
+
+			if httpResponse.StatusCode >= http.StatusBadRequest {
+				logErrorf("exporting statistics to InfluxDB failed: %s", httpResponse.Status)
+			}
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// timeoutMiddleware lets clients shorten the deadline of their request with
+// `?timeout=N` seconds, clamped to [1, HANDLER_TIMEOUT_SECONDS]. The deadline
+// covers the whole request, i.e. all domains of a batch together. A handler
+// giving up on the deadline before responding is answered with a 504 error.
+func (server *Server) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if !httpRequest.URL.Query().Has("timeout") {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		seconds, err := strconv.Atoi(httpRequest.URL.Query().Get("timeout"))
+
+		if err != nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, "Malformed URL query parameter `timeout`, expected a number of seconds")
+			return
+		}
+
+		timeout := min(max(time.Duration(seconds)*time.Second, time.Second), server.config().HandlerTimeout)
+
+		ctx, cancel := context.WithTimeout(httpRequest.Context(), timeout)
+		defer cancel()
+
+		statusHttpResponseWriter := &statusHttpResponseWriter{ResponseWriter: httpResponseWriter}
+
+		next.ServeHTTP(statusHttpResponseWriter, httpRequest.WithContext(ctx))
+
+		// The handlers stay silent once the context is done, as that usually
+		// means the client went away.
+		if statusHttpResponseWriter.statusCode == 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) && httpRequest.Context().Err() == nil {
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusGatewayTimeout, ErrorIdTimeout, fmt.Sprintf("Request exceeded its timeout of %s", timeout))
+		}
+	})
+}
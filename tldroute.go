@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+// forwardedByHeader marks lookups routed to the backend of their TLD, so that
+// a backend routing them back, e.g. through the load balancer in front of
+// this service, does not route them again and again.
+const forwardedByHeader = "X-Forwarded-By"
+
+// tldRouteHandler forwards the lookups of domains whose TLD has a backend in
+// routes, e.g. an internal TLD such as `.internal`, to that backend and
+// serves the rest with next.
+func (server *Server) tldRouteHandler(routes map[string]*url.URL, next http.Handler) http.Handler {
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	reverseProxies := map[string]*httputil.ReverseProxy{}
+
+	for tld, backend := range routes {
+		reverseProxy := httputil.NewSingleHostReverseProxy(backend)
+		reverseProxy.Transport = outboundTransport
+
+		reverseProxy.ErrorHandler = func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request, err error) {
+			logWarnf("routing lookup for %s to %s failed: %s", httpRequest.URL.Query().Get("domain"), backend, err)
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadGateway, ErrorIdUpstreamUnavailable, "Upstream is unavailable")
+		}
+
+		reverseProxies[tld] = reverseProxy
+	}
+
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		domain := strings.TrimSuffix(publicsuffix.NormalizeDomain(httpRequest.URL.Query().Get("domain")), ".")
+		tld := domain[strings.LastIndexByte(domain, '.')+1:]
+
+		reverseProxy, exists := reverseProxies[tld]
+
+		if !exists {
+			next.ServeHTTP(httpResponseWriter, httpRequest)
+			return
+		}
+
+		if forwardedBy := httpRequest.Header.Get(forwardedByHeader); forwardedBy != "" {
+			logWarnf("not routing lookup for %s again, it was already routed by %s", domain, forwardedBy)
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusLoopDetected, ErrorIdLoopDetected, fmt.Sprintf("Lookup for TLD `%s` was routed back to this service", tld))
+			return
+		}
+
+		httpRequest.Header.Set(forwardedByHeader, hostname)
+
+		reverseProxy.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
+
+// parseTLDRoutes parses TLD_ROUTE_MAP, a JSON object of TLDs and the URLs
+// of their backends, e.g. `{"internal": "http://internal-handler"}`.
+func parseTLDRoutes(tldRouteMap string) (map[string]*url.URL, error) {
+	rawRoutes := map[string]string{}
+
+	if err := json.Unmarshal([]byte(tldRouteMap), &rawRoutes); err != nil {
+		return nil, err
+	}
+
+	routes := make(map[string]*url.URL, len(rawRoutes))
+
+	for tld, rawBackend := range rawRoutes {
+		backend, err := url.Parse(rawBackend)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if backend.Scheme == "" || backend.Host == "" {
+			return nil, fmt.Errorf("backend %q of TLD %q is no absolute URL", rawBackend, tld)
+		}
+
+		routes[strings.TrimPrefix(publicsuffix.NormalizeDomain(tld), ".")] = backend
+	}
+
+	return routes, nil
+}
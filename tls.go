@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var tlsMinVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSMinVersion maps a TLS_MIN_VERSION value such as `1.3` onto its
+// crypto/tls constant and falls back to TLS 1.2 for anything unknown.
+func parseTLSMinVersion(value string) (uint16, string) {
+	if version, exists := tlsMinVersions[value]; exists {
+		return version, value
+	}
+
+	logWarnf("ignoring invalid TLS_MIN_VERSION %q, expected `1.2` or `1.3`", value)
+
+	return tls.VersionTLS12, "1.2"
+}
+
+// loadTLSCertificate loads the certificate from PEM encoded material if given,
+// as platforms like Heroku only offer environment variables, and from files
+// otherwise. It returns nil when neither is configured.
+func loadTLSCertificate(certFile string, keyFile string, certPEM string, keyPEM string) (*tls.Certificate, error) {
+	var certificate tls.Certificate
+	var err error
+
+	if certPEM != "" && keyPEM != "" {
+		certificate, err = tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	} else if certFile != "" && keyFile != "" {
+		certificate, err = tls.LoadX509KeyPair(certFile, keyFile)
+	} else {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	logInfof("TLS certificate for %s expires on %s", leaf.Subject.CommonName, leaf.NotAfter.Format(time.RFC3339))
+
+	certificate.Leaf = leaf
+
+	return &certificate, nil
+}
+
+// startTLSCertificateMonitor exposes the remaining validity of leaf as gauge
+// and warns every 24 hours once less than warnDays of it are left.
+func startTLSCertificateMonitor(leaf *x509.Certificate, warnDays int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "publicsuffix_tls_cert_expiry_seconds",
+		Help: "Seconds until the TLS certificate in use expires.",
+	}, func() float64 {
+		return time.Until(leaf.NotAfter).Seconds()
+	})
+
+	warnBefore := time.Duration(warnDays) * 24 * time.Hour
+
+	go func() {
+		for {
+			if remaining := time.Until(leaf.NotAfter); remaining < warnBefore {
+				logWarnf("TLS certificate for %s expires in %d days on %s", leaf.Subject.CommonName, int(remaining.Hours()/24), leaf.NotAfter.Format(time.RFC3339))
+			}
+
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+func newTLSConfig(minVersion uint16, certificate tls.Certificate) *tls.Config {
+	return &tls.Config{
+		MinVersion:   minVersion,
+		Certificates: []tls.Certificate{certificate},
+	}
+}
+
+// tlsVersionMiddleware rejects requests over connections that negotiated an
+// older TLS version than minVersion. The handshake already refuses those, so
+// this only guards against a misconfigured TLS config with an explicit error.
+//
+// See: https://www.rfc-editor.org/rfc/rfc9110#status.426
+func (server *Server) tlsVersionMiddleware(minVersion uint16, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		if httpRequest.TLS != nil && httpRequest.TLS.Version < minVersion {
+			// HTTP/2 forbids connection-specific headers such as Upgrade.
+			if httpRequest.ProtoMajor == 1 {
+				httpResponseWriter.Header().Set("Upgrade", strings.ReplaceAll(tls.VersionName(minVersion), " ", "/"))
+				httpResponseWriter.Header().Set("Connection", "Upgrade")
+			}
+
+			server.writeErrorHttpResponse(httpResponseWriter, http.StatusUpgradeRequired, ErrorIdTLSVersionTooOld, fmt.Sprintf("%s was negotiated, but at least %s is required: update the TLS library of the client", tls.VersionName(httpRequest.TLS.Version), tls.VersionName(minVersion)))
+			return
+		}
+
+		next.ServeHTTP(httpResponseWriter, httpRequest)
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestParseTLSMinVersion(t *testing.T) {
+	tests := []struct {
+		value       string
+		version     uint16
+		versionName string
+	}{
+		{"1.2", tls.VersionTLS12, "1.2"},
+		{"1.3", tls.VersionTLS13, "1.3"},
+		{"1.1", tls.VersionTLS12, "1.2"},
+		{"", tls.VersionTLS12, "1.2"},
+	}
+
+	for _, test := range tests {
+		version, versionName := parseTLSMinVersion(test.value)
+
+		if version != test.version || versionName != test.versionName {
+			t.Errorf("parseTLSMinVersion(%q) = %#x, %q, want %#x, %q", test.value, version, versionName, test.version, test.versionName)
+		}
+	}
+}
+
+func TestNewTLSConfigMinVersion(t *testing.T) {
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		if tlsConfig := newTLSConfig(version, tls.Certificate{}); tlsConfig.MinVersion != version {
+			t.Errorf("newTLSConfig(%#x).MinVersion = %#x", version, tlsConfig.MinVersion)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+const (
+	// maxTransactionLogBodyBytes caps the response body recorded per request.
+	maxTransactionLogBodyBytes = 4 << 10
+
+	// Of the domains of a batch, only the first maxTransactionLogSampleDomains
+	// and the errors of the first maxTransactionLogValidationErrors invalid
+	// ones are recorded.
+	maxTransactionLogSampleDomains    = 3
+	maxTransactionLogValidationErrors = 10
+)
+
+// redactedHeaders carry credentials, which must not end up in the log.
+var redactedHeaders = []string{"Authorization", "X-API-Key"}
+
+// redactedDomainPatterns match domains that look like they carry personal
+// data or secrets, e.g. `jane.doe@example.com`, `4915112345678.example.com`
+// or `3f2a9c0e1b7d4a6f.tunnel.example.com`.
+var redactedDomainPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`@`),
+	regexp.MustCompile(`\d{7,}`),
+	regexp.MustCompile(`(?i)[0-9a-f]{16,}`),
+}
+
+// domainTokenPattern matches whatever might be a domain or a mail address in
+// a response body, so that redactText can redact it like redactDomain.
+var domainTokenPattern = regexp.MustCompile(`[\p{L}\p{N}@._%+-]+`)
+
+type TransactionLogEntry struct {
+	Timestamp       time.Time   `json:"timestamp"`
+	Method          string      `json:"method"`
+	Path            string      `json:"path"`
+	Query           string      `json:"query"`
+	RequestHeaders  http.Header `json:"requestHeaders"`
+	StatusCode      int         `json:"statusCode"`
+	ResponseHeaders http.Header `json:"responseHeaders"`
+	DurationMs      float64     `json:"durationMs"`
+
+	// Only set for textual responses, with domains redacted like in
+	// SampleDomains.
+	ResponseBody string `json:"responseBody,omitempty"`
+
+	// Only set for batches sent with POST, see recordBatch.
+	BatchSize        int      `json:"batchSize,omitempty"`
+	SampleDomains    []string `json:"sampleDomains,omitempty"`
+	ValidationErrors []string `json:"validationErrors,omitempty"`
+}
+
+// TransactionLog appends every request and its response to a file as one
+// line of JSON, for deployments that need a full record for forensics. The
+// file is moved to `<path>.1` once it exceeds maxBytes.
+type TransactionLog struct {
+	path     string
+	maxBytes int64
+
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+}
+
+func openTransactionLog(path string, maxBytes int64) (*TransactionLog, error) {
+	transactionLog := &TransactionLog{path: path, maxBytes: maxBytes}
+
+	if err := transactionLog.open(); err != nil {
+		return nil, err
+	}
+
+	return transactionLog, nil
+}
+
+func (transactionLog *TransactionLog) open() error {
+	file, err := os.OpenFile(transactionLog.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	transactionLog.file = file
+	transactionLog.writer = bufio.NewWriter(file)
+	transactionLog.size = fileInfo.Size()
+
+	return nil
+}
+
+func (transactionLog *TransactionLog) rotate() error {
+	transactionLog.file.Close()
+
+	if err := os.Rename(transactionLog.path, transactionLog.path+".1"); err != nil {
+		return err
+	}
+
+	return transactionLog.open()
+}
+
+func (transactionLog *TransactionLog) append(transactionLogEntry TransactionLogEntry) {
+	line, err := json.Marshal(transactionLogEntry)
+
+	if err != nil {
+		logErrorf("Encoding transaction log entry failed: %s", err)
+		return
+	}
+
+	transactionLog.mutex.Lock()
+	defer transactionLog.mutex.Unlock()
+
+	if transactionLog.maxBytes > 0 && transactionLog.size > 0 && transactionLog.size+int64(len(line))+1 > transactionLog.maxBytes {
+		if err := transactionLog.rotate(); err != nil {
+			logErrorf("Rotating transaction log failed: %s", err)
+			return
+		}
+	}
+
+	transactionLog.writer.Write(line)
+	transactionLog.writer.WriteByte('\n')
+	transactionLog.size += int64(len(line)) + 1
+
+	// Entries are flushed one at a time, so that none are lost on a crash.
+	if err := transactionLog.writer.Flush(); err != nil {
+		logErrorf("Writing transaction log failed: %s", err)
+	}
+}
+
+// transactionHttpResponseWriter records the status code and the beginning
+// of the body of a response.
+type transactionHttpResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       []byte
+
+	batchSize        int
+	sampleDomains    []string
+	validationErrors []string
+}
+
+func (httpResponseWriter *transactionHttpResponseWriter) WriteHeader(statusCode int) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = statusCode
+	}
+
+	httpResponseWriter.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (httpResponseWriter *transactionHttpResponseWriter) Write(data []byte) (int, error) {
+	if httpResponseWriter.statusCode == 0 {
+		httpResponseWriter.statusCode = http.StatusOK
+	}
+
+	if missing := maxTransactionLogBodyBytes - len(httpResponseWriter.body); missing > 0 {
+		if missing > len(data) {
+			missing = len(data)
+		}
+
+		httpResponseWriter.body = append(httpResponseWriter.body, data[:missing]...)
+	}
+
+	return httpResponseWriter.ResponseWriter.Write(data)
+}
+
+func (httpResponseWriter *transactionHttpResponseWriter) Unwrap() http.ResponseWriter {
+	return httpResponseWriter.ResponseWriter
+}
+
+// isTextualContentType reports whether a body of contentType can be logged
+// as text, unlike e.g. Parquet files or images.
+func isTextualContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, ndjsonContentType) ||
+		strings.HasPrefix(contentType, multipartContentType)
+}
+
+func redactHeaders(header http.Header) http.Header {
+	header = header.Clone()
+
+	for _, name := range redactedHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, "REDACTED")
+		}
+	}
+
+	return header
+}
+
+func redactDomain(domain string) string {
+	for _, redactedDomainPattern := range redactedDomainPatterns {
+		if redactedDomainPattern.MatchString(domain) {
+			return "[redacted]"
+		}
+	}
+
+	return domain
+}
+
+// redactText redacts the domains in text, e.g. in the JSON of a lookup result,
+// which echoes the domain looked up.
+func redactText(text string) string {
+	return domainTokenPattern.ReplaceAllStringFunc(text, redactDomain)
+}
+
+// redactQuery redacts the values of a query string, e.g. the `domain` of a
+// lookup sent with GET.
+func redactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+
+	if err != nil {
+		return redactText(rawQuery)
+	}
+
+	for _, valuesOfName := range values {
+		for i, value := range valuesOfName {
+			valuesOfName[i] = redactDomain(value)
+		}
+	}
+
+	return values.Encode()
+}
+
+// recordBatch adds the size of a batch sent with POST, its first domains and
+// the validation errors of its invalid ones to the transaction log entry of
+// the request, as its body is not logged otherwise. Domains are redacted when
+// they look sensitive.
+func recordBatch(httpResponseWriter http.ResponseWriter, domains []string, options publicsuffix.Options) {
+	for {
+		switch current := httpResponseWriter.(type) {
+		case *transactionHttpResponseWriter:
+			current.batchSize = len(domains)
+
+			for _, domain := range domains[:min(len(domains), maxTransactionLogSampleDomains)] {
+				current.sampleDomains = append(current.sampleDomains, redactDomain(domain))
+			}
+
+			for _, domain := range domains {
+				if len(current.validationErrors) == maxTransactionLogValidationErrors {
+					break
+				}
+
+				if _, err := publicsuffix.Validate(domain, options); err != nil {
+					current.validationErrors = append(current.validationErrors, fmt.Sprintf("%s: %s", redactDomain(domain), err))
+				}
+			}
+
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			httpResponseWriter = current.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+func transactionLogMiddleware(transactionLog *TransactionLog, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		startTime := time.Now()
+		transactionHttpResponseWriter := &transactionHttpResponseWriter{ResponseWriter: httpResponseWriter}
+
+		next.ServeHTTP(transactionHttpResponseWriter, httpRequest)
+
+		if transactionHttpResponseWriter.statusCode == 0 {
+			transactionHttpResponseWriter.statusCode = http.StatusOK
+		}
+
+		transactionLogEntry := TransactionLogEntry{
+			Timestamp:       startTime.UTC(),
+			Method:          httpRequest.Method,
+			Path:            httpRequest.URL.Path,
+			Query:           redactQuery(httpRequest.URL.RawQuery),
+			RequestHeaders:  redactHeaders(httpRequest.Header),
+			StatusCode:      transactionHttpResponseWriter.statusCode,
+			ResponseHeaders: httpResponseWriter.Header().Clone(),
+			DurationMs:      float64(time.Since(startTime).Microseconds()) / 1000,
+
+			BatchSize:        transactionHttpResponseWriter.batchSize,
+			SampleDomains:    transactionHttpResponseWriter.sampleDomains,
+			ValidationErrors: transactionHttpResponseWriter.validationErrors,
+		}
+
+		if isTextualContentType(httpResponseWriter.Header().Get("Content-Type")) {
+			transactionLogEntry.ResponseBody = redactText(string(transactionHttpResponseWriter.body))
+		}
+
+		transactionLog.append(transactionLogEntry)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		rawQuery string
+		want     string
+	}{
+		{"domain=www.example.com", "domain=www.example.com"},
+		{"domain=jane.doe%40example.com", "domain=%5Bredacted%5D"},
+		{"domain=4915112345678.example.com&explain=true", "domain=%5Bredacted%5D&explain=true"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rawQuery, func(t *testing.T) {
+			if query := redactQuery(test.rawQuery); query != test.want {
+				t.Errorf("redactQuery(%q) = %q, want %q", test.rawQuery, query, test.want)
+			}
+		})
+	}
+}
+
+func TestTransactionLogRedactsLookups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.log")
+	transactionLog, err := openTransactionLog(path, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := transactionLogMiddleware(transactionLog, http.HandlerFunc(func(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+		httpResponseWriter.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(httpResponseWriter).Encode(map[string]string{
+			"domain":       httpRequest.URL.Query().Get("domain"),
+			"publicSuffix": "com",
+		})
+	}))
+
+	httpResponseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(httpResponseRecorder, httptest.NewRequest(http.MethodGet, "/publicsuffix?domain=3f2a9c0e1b7d4a6f.tunnel.example.com", nil))
+
+	if !strings.Contains(httpResponseRecorder.Body.String(), "3f2a9c0e1b7d4a6f") {
+		t.Fatalf("response = %s, want the domain unredacted", httpResponseRecorder.Body)
+	}
+
+	line, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(line), "3f2a9c0e1b7d4a6f") {
+		t.Errorf("transaction log = %s, want the domain redacted", line)
+	}
+
+	transactionLogEntry := TransactionLogEntry{}
+
+	if err := json.Unmarshal(line, &transactionLogEntry); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(transactionLogEntry.ResponseBody, `"publicSuffix":"com"`) {
+		t.Errorf("responseBody = %s, want the rest of the body kept", transactionLogEntry.ResponseBody)
+	}
+}
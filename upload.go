@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errUploadTooLarge = errors.New("file is too large")
+	errUploadNotUTF8  = errors.New("file is not UTF-8 encoded")
+)
+
+// readUploadedDomains reads the domains of a text file, one per line, skipping
+// blank lines and `#` comments. At most maxBytes of the file are read.
+func readUploadedDomains(file io.Reader, maxBytes int) ([]string, error) {
+	limitedFile := &io.LimitedReader{R: file, N: int64(maxBytes) + 1}
+	scanner := bufio.NewScanner(limitedFile)
+	domains := []string{}
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if limitedFile.N == 0 {
+			return nil, errUploadTooLarge
+		}
+
+		line := scanner.Text()
+
+		if !utf8.ValidString(line) {
+			return nil, fmt.Errorf("%w: invalid byte sequence in line %d", errUploadNotUTF8, lineNumber)
+		}
+
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domains = append(domains, line)
+	}
+
+	if limitedFile.N == 0 {
+		return nil, errUploadTooLarge
+	}
+
+	return domains, scanner.Err()
+}
+
+// uploadedFile returns the `file` field of a multipart/form-data request
+// without buffering the upload to memory or disk first.
+func uploadedFile(httpRequest *http.Request) (*multipart.Part, error) {
+	multipartReader, err := httpRequest.MultipartReader()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := multipartReader.NextPart()
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("missing field `file`")
+			}
+
+			return nil, err
+		}
+
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// batchUploadHttpHandler looks up the domains of a text file uploaded as the
+// `file` field of a multipart/form-data request and streams the results as
+// NDJSON, for datasets too large for a JSON body.
+func (server *Server) batchUploadHttpHandler(httpResponseWriter http.ResponseWriter, httpRequest *http.Request) {
+	if httpRequest.Method != http.MethodPost {
+		httpResponseWriter.Header().Set("Allow", "POST")
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusMethodNotAllowed, ErrorIdMethodNotAllowed, fmt.Sprintf("Method `%s` is not allowed at path `%s`", httpRequest.Method, httpRequest.URL.Path))
+		return
+	}
+
+	fields, err := parseFields(httpRequest)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedQuery, fmt.Sprintf("Malformed URL query parameter `fields`: %s", err))
+		return
+	}
+
+	// Leaves room for the multipart headers and boundaries around the file.
+	httpRequest.Body = http.MaxBytesReader(httpResponseWriter, httpRequest.Body, int64(server.config().UploadMaxBytes)+1<<20)
+
+	file, err := uploadedFile(httpRequest)
+
+	if err != nil {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedBody, fmt.Sprintf("Malformed multipart/form-data request body: %s", err))
+		return
+	}
+
+	domains, err := readUploadedDomains(file, server.config().UploadMaxBytes)
+
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, errUploadTooLarge), errors.As(err, &maxBytesError):
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusRequestEntityTooLarge, ErrorIdUploadTooLarge, fmt.Sprintf("Uploaded %s: the maximum is %d bytes", errUploadTooLarge, server.config().UploadMaxBytes))
+		return
+	case errors.Is(err, errUploadNotUTF8):
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusUnprocessableEntity, ErrorIdUploadNotUTF8, fmt.Sprintf("Uploaded %s", err))
+		return
+	case err != nil:
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedBody, fmt.Sprintf("Malformed uploaded file: %s", err))
+		return
+	}
+
+	if len(domains) == 0 {
+		server.writeErrorHttpResponse(httpResponseWriter, http.StatusBadRequest, ErrorIdMalformedBody, "Uploaded file contains no domains")
+		return
+	}
+
+	recordBatch(httpResponseWriter, domains, server.config().LookupOptions)
+
+	server.writeNDJSONBatchHttpResponse(httpResponseWriter, httpRequest, domains, fields)
+}
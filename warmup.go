@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+
+	"stefankuehnel/publicsuffix/publicsuffix"
+)
+
+var coalescedLookupsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "publicsuffix_coalesced_lookups_total",
+	Help: "Number of lookups sharing one result with identical concurrent lookups during warm-up.",
+})
+
+// LookupCoalescer lets identical concurrent lookups wait for the first one
+// and share its result, but only until the warm-up after startup is over, as
+// that is when a restarted instance is hit by everything its clients queued
+// up at once.
+type LookupCoalescer struct {
+	until time.Time
+	group singleflight.Group
+}
+
+func newLookupCoalescer(duration time.Duration) *LookupCoalescer {
+	return &LookupCoalescer{until: time.Now().Add(duration)}
+}
+
+// lookup looks up domain like publicsuffix.LookupContext does, coalescing it
+// with identical lookups during warm-up.
+func (lookupCoalescer *LookupCoalescer) lookup(ctx context.Context, domain string, options publicsuffix.Options) (publicsuffix.Result, error) {
+	if lookupCoalescer == nil || time.Now().After(lookupCoalescer.until) {
+		return publicsuffix.LookupContext(ctx, domain, options)
+	}
+
+	// Only these options differ between the lookups of the same config.
+	key := fmt.Sprintf("%s %t %t %t %t %t", domain, options.ResolveDNS, options.AllCandidates, options.Explain, options.Strict, options.Diagnostics)
+
+	result, err, shared := lookupCoalescer.group.Do(key, func() (any, error) {
+		// The lookup must not fail for everyone waiting on it when the client
+		// that started it goes away.
+		return publicsuffix.LookupContext(context.WithoutCancel(ctx), domain, options)
+	})
+
+	if shared {
+		coalescedLookupsTotal.Inc()
+	}
+
+	return result.(publicsuffix.Result), err
+}